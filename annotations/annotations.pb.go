@@ -9,6 +9,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	Interceptors
+	Parameter
 */
 package annotations
 
@@ -29,8 +30,10 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Interceptors struct {
-	Indexes          []string `protobuf:"bytes,1,rep,name=indexes" json:"indexes,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	Indexes          []string     `protobuf:"bytes,1,rep,name=indexes" json:"indexes,omitempty"`
+	Exclude          []string     `protobuf:"bytes,2,rep,name=exclude" json:"exclude,omitempty"`
+	Params           []*Parameter `protobuf:"bytes,3,rep,name=params" json:"params,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *Interceptors) Reset()                    { *m = Interceptors{} }
@@ -45,6 +48,53 @@ func (m *Interceptors) GetIndexes() []string {
 	return nil
 }
 
+func (m *Interceptors) GetExclude() []string {
+	if m != nil {
+		return m.Exclude
+	}
+	return nil
+}
+
+func (m *Interceptors) GetParams() []*Parameter {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+type Parameter struct {
+	Index            *string `protobuf:"bytes,1,opt,name=index" json:"index,omitempty"`
+	Name             *string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Value            *string `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Parameter) Reset()                    { *m = Parameter{} }
+func (m *Parameter) String() string            { return proto.CompactTextString(m) }
+func (*Parameter) ProtoMessage()               {}
+func (*Parameter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Parameter) GetIndex() string {
+	if m != nil && m.Index != nil {
+		return *m.Index
+	}
+	return ""
+}
+
+func (m *Parameter) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *Parameter) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
 var E_PackageInterceptors = &proto.ExtensionDesc{
 	ExtendedType:  (*google_protobuf.FileOptions)(nil),
 	ExtensionType: (*Interceptors)(nil),
@@ -74,6 +124,7 @@ var E_MethodInterceptors = &proto.ExtensionDesc{
 
 func init() {
 	proto.RegisterType((*Interceptors)(nil), "grpcmw.Interceptors")
+	proto.RegisterType((*Parameter)(nil), "grpcmw.Parameter")
 	proto.RegisterExtension(E_PackageInterceptors)
 	proto.RegisterExtension(E_ServiceInterceptors)
 	proto.RegisterExtension(E_MethodInterceptors)
@@ -82,19 +133,24 @@ func init() {
 func init() { proto.RegisterFile("annotations.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 215 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x4c, 0xcc, 0xcb, 0xcb,
-	0x2f, 0x49, 0x2c, 0xc9, 0xcc, 0xcf, 0x2b, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x4b,
-	0x2f, 0x2a, 0x48, 0xce, 0x2d, 0x97, 0x52, 0x48, 0xcf, 0xcf, 0x4f, 0xcf, 0x49, 0xd5, 0x07, 0x8b,
-	0x26, 0x95, 0xa6, 0xe9, 0xa7, 0xa4, 0x16, 0x27, 0x17, 0x65, 0x16, 0x94, 0xe4, 0x17, 0x41, 0x54,
-	0x2a, 0x69, 0x70, 0xf1, 0x78, 0xe6, 0x95, 0xa4, 0x16, 0x25, 0xa7, 0x82, 0x04, 0x8b, 0x85, 0x24,
-	0xb8, 0xd8, 0x33, 0xf3, 0x52, 0x52, 0x2b, 0x52, 0x8b, 0x25, 0x18, 0x15, 0x98, 0x35, 0x38, 0x83,
-	0x60, 0x5c, 0xab, 0x34, 0x2e, 0x91, 0x82, 0xc4, 0xe4, 0xec, 0xc4, 0xf4, 0xd4, 0xf8, 0x4c, 0x64,
-	0x1d, 0x32, 0x7a, 0x10, 0x4b, 0xf4, 0x60, 0x96, 0xe8, 0xb9, 0x65, 0xe6, 0xa4, 0xfa, 0x17, 0x80,
-	0xdd, 0x23, 0x31, 0x91, 0x43, 0x81, 0x51, 0x83, 0xdb, 0x48, 0x44, 0x0f, 0xe2, 0x22, 0x3d, 0x64,
-	0xcb, 0x82, 0x84, 0xa1, 0x06, 0x22, 0x0b, 0x5a, 0x65, 0x72, 0x89, 0x14, 0xa7, 0x16, 0x95, 0x65,
-	0x26, 0xa3, 0xd9, 0x23, 0x8f, 0x61, 0x4f, 0x30, 0x44, 0x19, 0x71, 0x56, 0x41, 0xcd, 0x44, 0xb1,
-	0x2a, 0x8d, 0x4b, 0x38, 0x37, 0xb5, 0x24, 0x23, 0x3f, 0x05, 0xd5, 0x26, 0x39, 0x0c, 0x9b, 0x7c,
-	0xc1, 0xaa, 0x88, 0xb2, 0x48, 0x08, 0x62, 0x22, 0xb2, 0x18, 0x20, 0x00, 0x00, 0xff, 0xff, 0xc0,
-	0x67, 0xfd, 0xf1, 0xa2, 0x01, 0x00, 0x00,
+	// 289 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x90, 0x41, 0x4e, 0xeb, 0x30,
+	0x18, 0x84, 0x95, 0x97, 0x47, 0xa1, 0x2e, 0x9b, 0xba, 0x59, 0x58, 0x08, 0x41, 0xd4, 0x55, 0xd8,
+	0xb8, 0x52, 0x97, 0x3d, 0x00, 0x12, 0x42, 0x08, 0x14, 0x0e, 0x80, 0x8c, 0xf3, 0x37, 0x58, 0x24,
+	0xb6, 0x65, 0x3b, 0xa5, 0xd7, 0xe0, 0xc6, 0xc8, 0x76, 0x82, 0x52, 0xba, 0xe9, 0x2e, 0x33, 0xff,
+	0x68, 0xbe, 0x8c, 0xd1, 0x9c, 0x49, 0xa9, 0x1c, 0x73, 0x42, 0x49, 0x4b, 0xb5, 0x51, 0x4e, 0xe1,
+	0x49, 0x6d, 0x34, 0x6f, 0xbf, 0xae, 0xf2, 0x5a, 0xa9, 0xba, 0x81, 0x55, 0x70, 0xdf, 0xbb, 0xed,
+	0xaa, 0x02, 0xcb, 0x8d, 0xd0, 0x4e, 0x99, 0x98, 0x5c, 0xb6, 0xe8, 0xf2, 0x41, 0x3a, 0x30, 0x1c,
+	0xbc, 0x69, 0x31, 0x41, 0xe7, 0x42, 0x56, 0xb0, 0x07, 0x4b, 0x92, 0x3c, 0x2d, 0xa6, 0xe5, 0x20,
+	0xfd, 0x05, 0xf6, 0xbc, 0xe9, 0x2a, 0x20, 0xff, 0xe2, 0xa5, 0x97, 0xf8, 0x0e, 0x4d, 0x34, 0x33,
+	0xac, 0xb5, 0x24, 0xcd, 0xd3, 0x62, 0xb6, 0x9e, 0xd3, 0x88, 0xa7, 0x2f, 0xde, 0x05, 0x07, 0xa6,
+	0xec, 0x03, 0xcb, 0x47, 0x34, 0xfd, 0x35, 0x71, 0x86, 0xce, 0x42, 0x39, 0x49, 0xf2, 0xa4, 0x98,
+	0x96, 0x51, 0x60, 0x8c, 0xfe, 0x4b, 0xd6, 0x7a, 0x88, 0x37, 0xc3, 0xb7, 0x4f, 0xee, 0x58, 0xd3,
+	0x01, 0x49, 0x63, 0x32, 0x88, 0xcd, 0x16, 0x65, 0x9a, 0xf1, 0x4f, 0x56, 0xc3, 0x9b, 0x18, 0x6f,
+	0xb8, 0xa6, 0x71, 0x36, 0x1d, 0x66, 0xd3, 0x7b, 0xd1, 0xc0, 0xb3, 0x0e, 0x2f, 0x44, 0xbe, 0x2f,
+	0xf2, 0xa4, 0x98, 0xad, 0xb3, 0xe1, 0x27, 0xc7, 0xf3, 0xcb, 0x45, 0x5f, 0x38, 0x36, 0x37, 0x02,
+	0x65, 0x16, 0xcc, 0x4e, 0xf0, 0x3f, 0x9c, 0xdb, 0x23, 0xce, 0x6b, 0x8c, 0x9d, 0x86, 0xea, 0x3b,
+	0x0f, 0x50, 0x5b, 0xb4, 0x68, 0xc1, 0x7d, 0xa8, 0xea, 0x90, 0x74, 0x73, 0x44, 0x7a, 0x0a, 0xa9,
+	0x93, 0x40, 0x38, 0x36, 0x8e, 0xbd, 0x9f, 0x01, 0x00, 0x5f, 0x69, 0x74, 0x7f, 0x34, 0x02, 0x00,
+	0x00,
 }
@@ -59,8 +59,11 @@ func startServer(port uint16) (*grpc.Server, net.Listener) {
 
 	// Create gRPC server and register the service
 	var e serverpb.Example
-	server := grpc.NewServer(grpc.UnaryInterceptor(r.UnaryResolver()))
+	server := grpc.NewServer(r.ServerOptions()...)
 	pb.RegisterServiceServer(server, &e)
+	if err := r.CheckWiring(server, grpcmw.WiringError); err != nil {
+		log.Fatalf("Invalid server router: %v", err)
+	}
 
 	// Start listening
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
@@ -84,9 +87,7 @@ func startClient(port uint16) (*grpc.ClientConn, pb.ServiceClient) {
 
 	// Setup connection to the server
 	target := fmt.Sprintf("127.0.0.1:%d", port)
-	conn, err := grpc.Dial(target,
-		grpc.WithInsecure(),
-		grpc.WithUnaryInterceptor(r.UnaryResolver()))
+	conn, err := grpc.Dial(target, append(r.DialOptions(), grpc.WithInsecure())...)
 	if err != nil {
 		log.Fatalf("Could not dial \"%s\": %v", target, err)
 	}
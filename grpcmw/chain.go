@@ -0,0 +1,482 @@
+package grpcmw
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// chainKind is what a chain needs to know about the type of its interceptors
+// (e.g. `grpc.UnaryServerInterceptor`), so that a single implementation of
+// chains is shared by the unary and stream chains of both server and client
+// sides. Interceptors are handled as `interface{}` holding a value of that
+// type.
+type chainKind struct {
+	// compile chains `arr` into a single interceptor.
+	compile func(arr []interface{}) interface{}
+	// interceptor returns the interceptor of `child`, a chain that has not
+	// been created by this package.
+	interceptor func(child interface{}) interface{}
+}
+
+// chainEntry is an element of a chain. It holds either an interceptor or a
+// reference to another chain.
+type chainEntry struct {
+	name        string
+	priority    Priority
+	interceptor interface{}
+	child       interface{}
+}
+
+// chain is a copy-on-write chain of interceptors: each modification publishes
+// a new immutable slice of entries, so that readers never lock. Writers are
+// serialized by `lock`, which also guards `frozen` and `mode`. It is embedded
+// by the typed chains of each side, which only convert interceptors from and
+// to their own type.
+type chain struct {
+	kind     *chainKind
+	entries  *atomic.Value // []chainEntry
+	compiled *atomic.Value // *compiledChain
+	frozen   bool
+	mode     FreezeMode
+	lock     *sync.Mutex
+}
+
+// compiledChain is a chain of interceptors compiled while `generation` was the
+// current generation of routes. If `frozen` is true, it has been compiled
+// after the chain has been frozen and never gets stale.
+type compiledChain struct {
+	interceptor interface{}
+	generation  uint64
+	frozen      bool
+}
+
+// chainer is implemented by the chains created by this package.
+type chainer interface {
+	core() *chain
+}
+
+// describer is implemented by the chains of every type.
+type describer interface {
+	Describe() []InterceptorInfo
+}
+
+// newChain returns a new chain of `kind` initialized with `arr`.
+func newChain(kind *chainKind, arr []interface{}) *chain {
+	c := &chain{
+		kind:     kind,
+		entries:  &atomic.Value{},
+		compiled: &atomic.Value{},
+		lock:     &sync.Mutex{},
+	}
+	c.entries.Store(newChainEntries(PhaseDefault, arr))
+	return c
+}
+
+// core returns the chain itself, so that the typed chains embedding it
+// implement `chainer`.
+func (c *chain) core() *chain {
+	return c
+}
+
+// coreOf returns the chain of `child` if it has been created by this package,
+// or nil.
+func coreOf(child interface{}) *chain {
+	if c, ok := child.(chainer); ok {
+		return c.core()
+	}
+	return nil
+}
+
+func newChainEntries(priority Priority, arr []interface{}) []chainEntry {
+	entries := make([]chainEntry, len(arr))
+	for idx, i := range arr {
+		entries[idx].priority = priority
+		entries[idx].interceptor = i
+	}
+	return entries
+}
+
+// spliceChainEntries returns a copy of `entries` where the `n` entries
+// starting at `idx` have been replaced by `arr`.
+func spliceChainEntries(entries []chainEntry, idx, n int, arr ...chainEntry) []chainEntry {
+	ret := make([]chainEntry, 0, len(entries)-n+len(arr))
+	ret = append(ret, entries[:idx]...)
+	ret = append(ret, arr...)
+	return append(ret, entries[idx+n:]...)
+}
+
+// sortChainEntries returns `entries` stably sorted by priority. It only makes
+// a copy if `entries` is not already sorted.
+func sortChainEntries(entries []chainEntry) []chainEntry {
+	less := func(i, j int) bool {
+		return entries[i].priority < entries[j].priority
+	}
+	if sort.SliceIsSorted(entries, less) {
+		return entries
+	}
+	entries = append([]chainEntry(nil), entries...)
+	sort.SliceStable(entries, less)
+	return entries
+}
+
+// containsChain returns whether `child` is referenced by one of `entries`.
+// Only chains created by this package are looked for.
+func containsChain(entries []chainEntry, child interface{}) bool {
+	if coreOf(child) == nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.child == child {
+			return true
+		}
+	}
+	return false
+}
+
+// indexChainEntry returns the position of the interceptor named `name` in
+// `entries`, or -1 if there is none.
+func indexChainEntry(entries []chainEntry, name string) int {
+	if name == "" {
+		return -1
+	}
+	for idx, entry := range entries {
+		if entry.name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// compile returns the current chain of interceptors compiled into a single
+// interceptor. It does not lock unless the chain has to be compiled again, in
+// which case the lock guarantees that the chain compiled by `Freeze` is never
+// replaced.
+func (c *chain) compile() interface{} {
+	generation := currentRoutesGeneration()
+	if compiled, ok := c.compiled.Load().(*compiledChain); ok && (compiled.frozen || compiled.generation == generation) {
+		return compiled.interceptor
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if compiled, ok := c.compiled.Load().(*compiledChain); ok && (compiled.frozen || compiled.generation >= generation) {
+		return compiled.interceptor
+	}
+	interceptor := c.kind.compile(appendChain(nil, c.kind, c, make(map[*chain]struct{}), nil))
+	c.compiled.Store(&compiledChain{
+		interceptor: interceptor,
+		generation:  generation,
+	})
+	return interceptor
+}
+
+// loadEntries returns the current entries of the chain. They must not be
+// modified.
+func (c *chain) loadEntries() []chainEntry {
+	return c.entries.Load().([]chainEntry)
+}
+
+// visit calls `fn` for each interceptor of the chain, in the order they have
+// been added, recursively expanding the chains it references. `path` holds the
+// names of the referenced chains leading to the interceptor. Chains that are in
+// `seen` are skipped so that a chain referenced multiple times is only called
+// once. Interceptors and chains named in `excluded` are skipped as well.
+func (c *chain) visit(path []string, seen map[*chain]struct{}, excluded map[string]struct{}, fn func(path []string, entry chainEntry)) {
+	if _, ok := seen[c]; ok {
+		return
+	}
+	seen[c] = struct{}{}
+	for _, entry := range c.loadEntries() {
+		if _, ok := excluded[entry.name]; ok && len(entry.name) > 0 {
+			continue
+		}
+		if entry.child != nil {
+			visitChain(c.kind, entry, appendPath(path, entry.name), seen, excluded, fn)
+		} else {
+			fn(path, entry)
+		}
+	}
+}
+
+// Describe returns the description of the elements of the chain sorted by
+// priority. Chains added by reference are described recursively, each of them
+// being sorted on its own: this is the structure of the chain, not the order in
+// which its interceptors are called, as priorities apply across the chains it
+// references (see `flattenChain`).
+func (c *chain) Describe() []InterceptorInfo {
+	return c.describe(make(map[*chain]struct{}))
+}
+
+// describe is the recursive implementation of `Describe`. `parents` holds the
+// chains being described so that cycles are not followed.
+func (c *chain) describe(parents map[*chain]struct{}) []InterceptorInfo {
+	parents[c] = struct{}{}
+	defer delete(parents, c)
+	entries := sortChainEntries(c.loadEntries())
+	infos := make([]InterceptorInfo, len(entries))
+	for idx, entry := range entries {
+		infos[idx] = InterceptorInfo{
+			Name:      entry.name,
+			Priority:  entry.priority,
+			Reference: entry.child != nil,
+		}
+		if child := coreOf(entry.child); child != nil {
+			if _, cycle := parents[child]; !cycle {
+				infos[idx].Chain = child.describe(parents)
+			}
+		} else if entry.child != nil {
+			infos[idx].Chain = entry.child.(describer).Describe()
+		}
+	}
+	return infos
+}
+
+// Freeze makes the chain immutable and compiles it once and for all. Later
+// modifications either fail with `ErrFrozen` or panic, depending on `mode`.
+// Methods that do not return any error ignore modifications in `FreezeError`
+// mode and report them with `grpclog`. The chains it references are not
+// frozen, as they may be shared with other chains, but the frozen chain keeps
+// on calling the interceptors they had when it was frozen.
+func (c *chain) Freeze(mode FreezeMode) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.frozen {
+		return
+	}
+	c.frozen, c.mode = true, mode
+	c.compiled.Store(&compiledChain{
+		interceptor: c.kind.compile(appendChain(nil, c.kind, c, make(map[*chain]struct{}), nil)),
+		frozen:      true,
+	})
+}
+
+// Frozen returns whether the chain has been frozen.
+func (c *chain) Frozen() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.frozen
+}
+
+// checkFrozen returns `ErrFrozen`, or panics with it, if the chain has been
+// frozen. It must be called with the lock held.
+func (c *chain) checkFrozen() error {
+	if !c.frozen {
+		return nil
+	}
+	return frozenError(c.mode)
+}
+
+// setEntries publishes `entries` as the new chain of interceptors. It must be
+// called with the lock held and `entries` must not be modified afterwards.
+func (c *chain) setEntries(entries []chainEntry) {
+	c.entries.Store(entries)
+	invalidateRoutes()
+}
+
+// add adds `arr` to the chain of interceptors with `priority` as their
+// priority.
+func (c *chain) add(priority Priority, arr []interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.frozen {
+		ignoreFrozen(c.mode)
+		return
+	}
+	entries := c.loadEntries()
+	c.setEntries(spliceChainEntries(entries, len(entries), 0, newChainEntries(priority, arr)...))
+}
+
+// addChildren adds `arr` to the chain of interceptors by reference:
+// interceptors that are added to any of them later on are called as well.
+// Chains that are already referenced are not added again. They have
+// `PhaseDefault` as their priority.
+func (c *chain) addChildren(arr []interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.frozen {
+		ignoreFrozen(c.mode)
+		return
+	}
+	entries := c.loadEntries()
+	for _, child := range arr {
+		if !containsChain(entries, child) {
+			entries = spliceChainEntries(entries, len(entries), 0, chainEntry{priority: PhaseDefault, child: child})
+		}
+	}
+	c.setEntries(entries)
+}
+
+// addNamed adds `i` to the chain of interceptors under `name` with `priority`
+// as its priority. If an interceptor has already been added under `name`, it
+// is replaced in place.
+func (c *chain) addNamed(name string, priority Priority, i interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.frozen {
+		ignoreFrozen(c.mode)
+		return
+	}
+	c.setNamedEntry(chainEntry{name: name, priority: priority, interceptor: i})
+}
+
+// addNamedChild adds `child` to the chain of interceptors by reference under
+// `name`. If `child` is already referenced, nothing is done. Otherwise, if an
+// interceptor has already been added under `name`, it is replaced in place.
+func (c *chain) addNamedChild(name string, child interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.frozen {
+		ignoreFrozen(c.mode)
+		return
+	}
+	if !containsChain(c.loadEntries(), child) {
+		c.setNamedEntry(chainEntry{name: name, priority: PhaseDefault, child: child})
+	}
+}
+
+// merge adds `child` to the chain by reference under `name`, unless it is
+// already referenced. Unlike `addNamedChild`, entries that have already been
+// added under `name` are kept: two different chains merged under the same name
+// are both called.
+func (c *chain) merge(name string, child interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.frozen {
+		ignoreFrozen(c.mode)
+		return
+	}
+	entries := c.loadEntries()
+	if !containsChain(entries, child) {
+		c.setEntries(spliceChainEntries(entries, len(entries), 0, chainEntry{name: name, priority: PhaseDefault, child: child}))
+	}
+}
+
+// setNamedEntry replaces the entry that has the same name as `entry` or, if
+// there is none, appends `entry` to the chain. It must be called with the lock
+// held.
+func (c *chain) setNamedEntry(entry chainEntry) {
+	entries := c.loadEntries()
+	if idx := indexChainEntry(entries, entry.name); idx >= 0 {
+		c.setEntries(spliceChainEntries(entries, idx, 1, entry))
+	} else {
+		c.setEntries(spliceChainEntries(entries, len(entries), 0, entry))
+	}
+}
+
+// Remove removes the interceptor named `name` from the chain. It returns
+// `ErrInterceptorNotFound` if there is none.
+func (c *chain) Remove(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	entries := c.loadEntries()
+	idx := indexChainEntry(entries, name)
+	if idx < 0 {
+		return ErrInterceptorNotFound
+	}
+	c.setEntries(spliceChainEntries(entries, idx, 1))
+	return nil
+}
+
+// replace replaces the interceptor named `name` with `i`, keeping its name,
+// its priority and its position in the chain. It returns
+// `ErrInterceptorNotFound` if there is none.
+func (c *chain) replace(name string, i interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	entries := c.loadEntries()
+	idx := indexChainEntry(entries, name)
+	if idx < 0 {
+		return ErrInterceptorNotFound
+	}
+	entry := entries[idx]
+	entry.interceptor, entry.child = i, nil
+	c.setEntries(spliceChainEntries(entries, idx, 1, entry))
+	return nil
+}
+
+// insert inserts `arr` at `offset` from the position of the interceptor named
+// `name`, with the same priority. It returns `ErrInterceptorNotFound` if there
+// is none.
+func (c *chain) insert(name string, offset int, arr []interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	entries := c.loadEntries()
+	idx := indexChainEntry(entries, name)
+	if idx < 0 {
+		return ErrInterceptorNotFound
+	}
+	c.setEntries(spliceChainEntries(entries, idx+offset, 0, newChainEntries(entries[idx].priority, arr)...))
+	return nil
+}
+
+// visitChain calls `fn` for each interceptor of the chain referenced by
+// `entry`, a chain of `kind` (see `visit`). Chains that have not been created
+// by this package are considered as a single interceptor with the priority of
+// `entry`.
+func visitChain(kind *chainKind, entry chainEntry, path []string, seen map[*chain]struct{}, excluded map[string]struct{}, fn func(path []string, entry chainEntry)) {
+	if c := coreOf(entry.child); c != nil {
+		c.visit(path, seen, excluded, fn)
+		return
+	}
+	fn(path, chainEntry{priority: entry.priority, interceptor: kind.interceptor(entry.child)})
+}
+
+// chainLeaf is an interceptor called by a chain, along with the names of the
+// referenced chains leading to it.
+type chainLeaf struct {
+	path  []string
+	entry chainEntry
+}
+
+// flattenChain returns the interceptors called by `child`, a chain of `kind`,
+// in the order they are called: the chains it references are expanded first,
+// and all the interceptors are then stably sorted by priority, so that the
+// priority of an interceptor applies across the chains merged into the same
+// level.
+func flattenChain(kind *chainKind, child interface{}, seen map[*chain]struct{}, excluded map[string]struct{}) []chainLeaf {
+	var leaves []chainLeaf
+	visitChain(kind, chainEntry{priority: PhaseDefault, child: child}, nil, seen, excluded, func(path []string, entry chainEntry) {
+		leaves = append(leaves, chainLeaf{path: path, entry: entry})
+	})
+	sort.SliceStable(leaves, func(i, j int) bool {
+		return leaves[i].entry.priority < leaves[j].entry.priority
+	})
+	return leaves
+}
+
+// resolveChain appends the description of each interceptor called by `child`
+// to `arr`, `level` being the index of the level `child` belongs to.
+func resolveChain(arr []ResolvedInterceptor, level string, kind *chainKind, child interface{}, seen map[*chain]struct{}, excluded map[string]struct{}) []ResolvedInterceptor {
+	for _, leaf := range flattenChain(kind, child, seen, excluded) {
+		arr = append(arr, ResolvedInterceptor{
+			Level:    level,
+			Chains:   leaf.path,
+			Name:     leaf.entry.name,
+			Priority: leaf.entry.priority,
+		})
+	}
+	return arr
+}
+
+// countChain returns the number of interceptors called by `child`.
+func countChain(kind *chainKind, child interface{}) int {
+	return len(appendChain(nil, kind, child, make(map[*chain]struct{}), nil))
+}
+
+// appendChain appends the interceptors called by `child` to `arr`, in the
+// order they are called (see `flattenChain`).
+func appendChain(arr []interface{}, kind *chainKind, child interface{}, seen map[*chain]struct{}, excluded map[string]struct{}) []interface{} {
+	for _, leaf := range flattenChain(kind, child, seen, excluded) {
+		arr = append(arr, leaf.entry.interceptor)
+	}
+	return arr
+}
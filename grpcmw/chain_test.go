@@ -0,0 +1,53 @@
+package grpcmw
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestChainFreezeWhileCompiling is meant to be run with `-race`: it freezes a
+// chain while it is being compiled again, and checks that the chain compiled by
+// `Freeze` is the one that is kept.
+func TestChainFreezeWhileCompiling(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		var calls []string
+		child := NewUnaryServerInterceptor(recordServerUnary(&calls, "frozen"))
+		parent := NewUnaryServerInterceptor().AddInterceptor(child)
+		for j := 0; j < 10; j++ {
+			parent.AddInterceptor(NewUnaryServerInterceptor())
+		}
+		core := coreOf(parent)
+
+		var (
+			wg      sync.WaitGroup
+			started = make(chan struct{})
+			done    = make(chan struct{})
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for compiled := 0; ; compiled++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if compiled == 1 {
+					close(started)
+				}
+				invalidateRoutes()
+				core.compile()
+			}
+		}()
+		<-started
+		parent.Freeze(FreezeError)
+		close(done)
+		wg.Wait()
+
+		child.AddGRPCInterceptor(recordServerUnary(&calls, "added"))
+		got := callServerUnary(t, parent.Interceptor(), &calls, "/pkg.Service/Method")
+		if !equalStrings(got, []string{"frozen"}) {
+			t.Fatalf("frozen chain called %q, want the interceptors it has been frozen with", got)
+		}
+	}
+}
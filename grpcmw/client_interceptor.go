@@ -1,8 +1,6 @@
 package grpcmw
 
 import (
-	"sync"
-
 	"golang.org/x/net/context"
 
 	"google.golang.org/grpc"
@@ -17,9 +15,46 @@ type StreamClientInterceptor interface {
 	Interceptor() grpc.StreamClientInterceptor
 	// AddGRPCInterceptor adds given interceptors to the chain.
 	AddGRPCInterceptor(i ...grpc.StreamClientInterceptor) StreamClientInterceptor
-	// AddInterceptor is a convenient way for adding `StreamClientInterceptor`
-	// to the chain of interceptors.
+	// AddGRPCInterceptorWithPriority adds given interceptors to the chain with
+	// `priority` as their priority.
+	AddGRPCInterceptorWithPriority(priority Priority, i ...grpc.StreamClientInterceptor) StreamClientInterceptor
+	// AddInterceptor adds given chains of interceptors to the chain. They are
+	// kept by reference, so interceptors added to them later on are also
+	// called.
 	AddInterceptor(i ...StreamClientInterceptor) StreamClientInterceptor
+	// AddNamed adds `i` to the chain under `name` so that it can later be
+	// removed, replaced or used as a position for insertions.
+	AddNamed(name string, i grpc.StreamClientInterceptor) StreamClientInterceptor
+	// AddNamedWithPriority is the same as `AddNamed` with `priority` as the
+	// priority of `i`.
+	AddNamedWithPriority(name string, priority Priority, i grpc.StreamClientInterceptor) StreamClientInterceptor
+	// AddNamedInterceptor adds the chain of interceptors `i` to the chain under
+	// `name`. It is kept by reference like with `AddInterceptor`.
+	AddNamedInterceptor(name string, i StreamClientInterceptor) StreamClientInterceptor
+	// AddMiddleware adds the `grpc.StreamClientInterceptor` of each given middleware
+	// to the chain. Middlewares that do not define any are ignored.
+	AddMiddleware(m ...Middleware) StreamClientInterceptor
+	// Remove removes the interceptor named `name` from the chain.
+	Remove(name string) error
+	// Replace replaces the interceptor named `name` with `i`.
+	Replace(name string, i grpc.StreamClientInterceptor) error
+	// InsertBefore inserts given interceptors right before the interceptor
+	// named `name`.
+	InsertBefore(name string, i ...grpc.StreamClientInterceptor) error
+	// InsertAfter inserts given interceptors right after the interceptor named
+	// `name`.
+	InsertAfter(name string, i ...grpc.StreamClientInterceptor) error
+	// Describe returns the description of the elements of the chain sorted by
+	// priority, the chains it references being described and sorted on their
+	// own. Since priorities apply across the referenced chains, this is not
+	// necessarily the order in which the interceptors are called (see
+	// `ClientRouter.Resolve`).
+	Describe() []InterceptorInfo
+	// Freeze makes the chain immutable. `mode` defines how later
+	// modifications are handled. The chains it references are not frozen.
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the chain has been frozen.
+	Frozen() bool
 }
 
 // UnaryClientInterceptor represents a client interceptor for gRPC methods that
@@ -29,36 +64,111 @@ type UnaryClientInterceptor interface {
 	// Interceptor chains all added interceptors into a single
 	// `grpc.UnaryClientInterceptor`.
 	Interceptor() grpc.UnaryClientInterceptor
-	// AddGRPCInterceptor adds `arr` to the chain of interceptors.
+	// AddGRPCInterceptor adds given interceptors to the chain.
 	AddGRPCInterceptor(i ...grpc.UnaryClientInterceptor) UnaryClientInterceptor
-	// AddInterceptor is a convenient way for adding `UnaryClientInterceptor`
-	// to the chain of interceptors.
+	// AddGRPCInterceptorWithPriority adds given interceptors to the chain with
+	// `priority` as their priority.
+	AddGRPCInterceptorWithPriority(priority Priority, i ...grpc.UnaryClientInterceptor) UnaryClientInterceptor
+	// AddInterceptor adds given chains of interceptors to the chain. They are
+	// kept by reference, so interceptors added to them later on are also
+	// called.
 	AddInterceptor(i ...UnaryClientInterceptor) UnaryClientInterceptor
+	// AddNamed adds `i` to the chain under `name` so that it can later be
+	// removed, replaced or used as a position for insertions.
+	AddNamed(name string, i grpc.UnaryClientInterceptor) UnaryClientInterceptor
+	// AddNamedWithPriority is the same as `AddNamed` with `priority` as the
+	// priority of `i`.
+	AddNamedWithPriority(name string, priority Priority, i grpc.UnaryClientInterceptor) UnaryClientInterceptor
+	// AddNamedInterceptor adds the chain of interceptors `i` to the chain under
+	// `name`. It is kept by reference like with `AddInterceptor`.
+	AddNamedInterceptor(name string, i UnaryClientInterceptor) UnaryClientInterceptor
+	// AddMiddleware adds the `grpc.UnaryClientInterceptor` of each given middleware
+	// to the chain. Middlewares that do not define any are ignored.
+	AddMiddleware(m ...Middleware) UnaryClientInterceptor
+	// Remove removes the interceptor named `name` from the chain.
+	Remove(name string) error
+	// Replace replaces the interceptor named `name` with `i`.
+	Replace(name string, i grpc.UnaryClientInterceptor) error
+	// InsertBefore inserts given interceptors right before the interceptor
+	// named `name`.
+	InsertBefore(name string, i ...grpc.UnaryClientInterceptor) error
+	// InsertAfter inserts given interceptors right after the interceptor named
+	// `name`.
+	InsertAfter(name string, i ...grpc.UnaryClientInterceptor) error
+	// Describe returns the description of the elements of the chain sorted by
+	// priority, the chains it references being described and sorted on their
+	// own. Since priorities apply across the referenced chains, this is not
+	// necessarily the order in which the interceptors are called (see
+	// `ClientRouter.Resolve`).
+	Describe() []InterceptorInfo
+	// Freeze makes the chain immutable. `mode` defines how later
+	// modifications are handled. The chains it references are not frozen.
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the chain has been frozen.
+	Frozen() bool
 }
 
+// streamClientInterceptor is a chain of `grpc.StreamClientInterceptor`.
 type streamClientInterceptor struct {
-	lock         *sync.RWMutex
-	interceptors []grpc.StreamClientInterceptor
+	*chain
 }
 
-type unaryClientInterceptor struct {
-	lock         *sync.RWMutex
-	interceptors []grpc.UnaryClientInterceptor
+// streamClientKind describes the chains of `grpc.StreamClientInterceptor`.
+var streamClientKind = &chainKind{
+	compile: func(arr []interface{}) interface{} {
+		interceptors := make([]grpc.StreamClientInterceptor, len(arr))
+		for idx, i := range arr {
+			interceptors[idx] = i.(grpc.StreamClientInterceptor)
+		}
+		return compileStreamClientInterceptors(interceptors)
+	},
+	interceptor: func(child interface{}) interface{} {
+		return child.(StreamClientInterceptor).Interceptor()
+	},
 }
 
 // NewStreamClientInterceptor returns a new `StreamClientInterceptor`.
 // It initializes its interceptor chain with `arr`.
 // This implementation is thread-safe.
+//
+// Interceptors are called by ascending priority and, for a given priority, in
+// the order they have been added.
 func NewStreamClientInterceptor(arr ...grpc.StreamClientInterceptor) StreamClientInterceptor {
-	return &streamClientInterceptor{
-		interceptors: arr,
-		lock:         &sync.RWMutex{},
+	return &streamClientInterceptor{chain: newChain(streamClientKind, streamClientInterceptors(arr))}
+}
+
+// streamClientInterceptors converts `arr` for the methods of `chain`.
+func streamClientInterceptors(arr []grpc.StreamClientInterceptor) []interface{} {
+	ret := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		ret[idx] = i
 	}
+	return ret
 }
 
-func chainStreamClientInterceptor(current grpc.StreamClientInterceptor, next grpc.Streamer) grpc.Streamer {
+func chainStreamClientInterceptor(arr []grpc.StreamClientInterceptor, idx int, streamer grpc.Streamer) grpc.Streamer {
+	if idx == len(arr) {
+		return streamer
+	}
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		return current(ctx, desc, cc, method, next, opts...)
+		return arr[idx](ctx, desc, cc, method, chainStreamClientInterceptor(arr, idx+1, streamer), opts...)
+	}
+}
+
+// compileStreamClientInterceptors chains `arr` into a single
+// `grpc.StreamClientInterceptor`. The streamer of each element is only built
+// when the previous element calls it.
+func compileStreamClientInterceptors(arr []grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	switch len(arr) {
+	case 0:
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+	case 1:
+		return arr[0]
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return arr[0](ctx, desc, cc, method, chainStreamClientInterceptor(arr, 1, streamer), opts...)
 	}
 }
 
@@ -67,89 +177,257 @@ func chainStreamClientInterceptor(current grpc.StreamClientInterceptor, next grp
 //
 // The `streamer` passed to each interceptor is either the next interceptor or,
 // for the last element of the chain, the target method.
-func (si streamClientInterceptor) Interceptor() grpc.StreamClientInterceptor {
+//
+// The chain is compiled once and compiled again only when it or any of the
+// chains it references is modified.
+func (c *streamClientInterceptor) Interceptor() grpc.StreamClientInterceptor {
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		// TODO: Find a more efficient way
-		interceptor := streamer
-		si.lock.RLock()
-		for idx := len(si.interceptors) - 1; idx >= 0; idx-- {
-			interceptor = chainStreamClientInterceptor(si.interceptors[idx], interceptor)
-		}
-		si.lock.RUnlock()
-		return interceptor(ctx, desc, cc, method, opts...)
+		return c.compile().(grpc.StreamClientInterceptor)(ctx, desc, cc, method, streamer, opts...)
+	}
+}
+
+// AddGRPCInterceptor adds `arr` to the chain of interceptors with
+// `PhaseDefault` as their priority.
+func (c *streamClientInterceptor) AddGRPCInterceptor(arr ...grpc.StreamClientInterceptor) StreamClientInterceptor {
+	return c.AddGRPCInterceptorWithPriority(PhaseDefault, arr...)
+}
+
+// AddGRPCInterceptorWithPriority adds `arr` to the chain of interceptors with
+// `priority` as their priority.
+func (c *streamClientInterceptor) AddGRPCInterceptorWithPriority(priority Priority, arr ...grpc.StreamClientInterceptor) StreamClientInterceptor {
+	c.add(priority, streamClientInterceptors(arr))
+	return c
+}
+
+// AddInterceptor adds `arr` to the chain of interceptors by reference:
+// interceptors that are added to any of them later on are called as well.
+// Chains that are already referenced are not added again. They have
+// `PhaseDefault` as their priority.
+func (c *streamClientInterceptor) AddInterceptor(arr ...StreamClientInterceptor) StreamClientInterceptor {
+	children := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		children[idx] = i
 	}
+	c.addChildren(children)
+	return c
 }
 
-// AddGRPCInterceptor adds `arr` to the chain of interceptors.
-func (si *streamClientInterceptor) AddGRPCInterceptor(arr ...grpc.StreamClientInterceptor) StreamClientInterceptor {
-	si.lock.Lock()
-	defer si.lock.Unlock()
-	si.interceptors = append(si.interceptors, arr...)
-	return si
+// AddNamed adds `i` to the chain of interceptors under `name` with
+// `PhaseDefault` as its priority. If an interceptor has already been added
+// under `name`, it is replaced in place.
+func (c *streamClientInterceptor) AddNamed(name string, i grpc.StreamClientInterceptor) StreamClientInterceptor {
+	return c.AddNamedWithPriority(name, PhaseDefault, i)
 }
 
-// AddInterceptor is a convenient way for adding `StreamClientInterceptor`
-// to the chain of interceptors. It only calls the method `Interceptor`
-// for each of them and append the return value to the chain.
-func (si *streamClientInterceptor) AddInterceptor(arr ...StreamClientInterceptor) StreamClientInterceptor {
-	si.lock.Lock()
-	defer si.lock.Unlock()
-	for _, i := range arr {
-		si.interceptors = append(si.interceptors, i.Interceptor())
+// AddNamedWithPriority adds `i` to the chain of interceptors under `name` with
+// `priority` as its priority. If an interceptor has already been added under
+// `name`, it is replaced in place.
+func (c *streamClientInterceptor) AddNamedWithPriority(name string, priority Priority, i grpc.StreamClientInterceptor) StreamClientInterceptor {
+	c.addNamed(name, priority, i)
+	return c
+}
+
+// AddMiddleware adds the `grpc.StreamClientInterceptor` of each given middleware to the
+// chain with the priority of the middleware, under its name if it has one.
+// Middlewares that do not define any `grpc.StreamClientInterceptor` are ignored.
+func (c *streamClientInterceptor) AddMiddleware(arr ...Middleware) StreamClientInterceptor {
+	for _, m := range arr {
+		switch {
+		case m.StreamClient == nil:
+		case m.Name == "":
+			c.AddGRPCInterceptorWithPriority(m.Priority, m.StreamClient)
+		default:
+			c.AddNamedWithPriority(m.Name, m.Priority, m.StreamClient)
+		}
 	}
-	return si
+	return c
+}
+
+// AddNamedInterceptor adds `i` to the chain of interceptors by reference under
+// `name`. If `i` is already referenced, nothing is done. Otherwise, if an
+// interceptor has already been added under `name`, it is replaced in place.
+func (c *streamClientInterceptor) AddNamedInterceptor(name string, i StreamClientInterceptor) StreamClientInterceptor {
+	c.addNamedChild(name, i)
+	return c
+}
+
+// Replace replaces the interceptor named `name` with `i`, keeping its name,
+// its priority and its position in the chain. It returns
+// `ErrInterceptorNotFound` if there is none.
+func (c *streamClientInterceptor) Replace(name string, i grpc.StreamClientInterceptor) error {
+	return c.replace(name, i)
+}
+
+// InsertBefore inserts `arr` right before the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *streamClientInterceptor) InsertBefore(name string, arr ...grpc.StreamClientInterceptor) error {
+	return c.insert(name, 0, streamClientInterceptors(arr))
+}
+
+// InsertAfter inserts `arr` right after the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *streamClientInterceptor) InsertAfter(name string, arr ...grpc.StreamClientInterceptor) error {
+	return c.insert(name, 1, streamClientInterceptors(arr))
+}
+
+// unaryClientInterceptor is a chain of `grpc.UnaryClientInterceptor`.
+type unaryClientInterceptor struct {
+	*chain
+}
+
+// unaryClientKind describes the chains of `grpc.UnaryClientInterceptor`.
+var unaryClientKind = &chainKind{
+	compile: func(arr []interface{}) interface{} {
+		interceptors := make([]grpc.UnaryClientInterceptor, len(arr))
+		for idx, i := range arr {
+			interceptors[idx] = i.(grpc.UnaryClientInterceptor)
+		}
+		return compileUnaryClientInterceptors(interceptors)
+	},
+	interceptor: func(child interface{}) interface{} {
+		return child.(UnaryClientInterceptor).Interceptor()
+	},
 }
 
 // NewUnaryClientInterceptor returns a new `UnaryClientInterceptor`.
 // It initializes its interceptor chain with `arr`.
 // This implementation is thread-safe.
+//
+// Interceptors are called by ascending priority and, for a given priority, in
+// the order they have been added.
 func NewUnaryClientInterceptor(arr ...grpc.UnaryClientInterceptor) UnaryClientInterceptor {
-	return &unaryClientInterceptor{
-		interceptors: arr,
-		lock:         &sync.RWMutex{},
+	return &unaryClientInterceptor{chain: newChain(unaryClientKind, unaryClientInterceptors(arr))}
+}
+
+// unaryClientInterceptors converts `arr` for the methods of `chain`.
+func unaryClientInterceptors(arr []grpc.UnaryClientInterceptor) []interface{} {
+	ret := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		ret[idx] = i
 	}
+	return ret
 }
 
-func chainUnaryClientInterceptor(current grpc.UnaryClientInterceptor, next grpc.UnaryInvoker) grpc.UnaryInvoker {
+func chainUnaryClientInterceptor(arr []grpc.UnaryClientInterceptor, idx int, invoker grpc.UnaryInvoker) grpc.UnaryInvoker {
+	if idx == len(arr) {
+		return invoker
+	}
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
-		return current(ctx, method, req, reply, cc, next, opts...)
+		return arr[idx](ctx, method, req, reply, cc, chainUnaryClientInterceptor(arr, idx+1, invoker), opts...)
+	}
+}
+
+// compileUnaryClientInterceptors chains `arr` into a single
+// `grpc.UnaryClientInterceptor`. The invoker of each element is only built
+// when the previous element calls it.
+func compileUnaryClientInterceptors(arr []grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	switch len(arr) {
+	case 0:
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	case 1:
+		return arr[0]
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return arr[0](ctx, method, req, reply, cc, chainUnaryClientInterceptor(arr, 1, invoker), opts...)
 	}
 }
 
 // Interceptor chains all added interceptors into a single
 // `grpc.UnaryClientInterceptor`.
 //
-// The `streamer` passed to each interceptor is either the next interceptor or,
+// The `invoker` passed to each interceptor is either the next interceptor or,
 // for the last element of the chain, the target method.
-func (ui *unaryClientInterceptor) Interceptor() grpc.UnaryClientInterceptor {
+//
+// The chain is compiled once and compiled again only when it or any of the
+// chains it references is modified.
+func (c *unaryClientInterceptor) Interceptor() grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		// TODO: Find a more efficient way
-		interceptor := invoker
-		ui.lock.RLock()
-		for idx := len(ui.interceptors) - 1; idx >= 0; idx-- {
-			interceptor = chainUnaryClientInterceptor(ui.interceptors[idx], interceptor)
-		}
-		ui.lock.RUnlock()
-		return interceptor(ctx, method, req, reply, cc, opts...)
+		return c.compile().(grpc.UnaryClientInterceptor)(ctx, method, req, reply, cc, invoker, opts...)
 	}
 }
 
-// AddGRPCInterceptor adds `arr` to the chain of interceptors.
-func (ui *unaryClientInterceptor) AddGRPCInterceptor(arr ...grpc.UnaryClientInterceptor) UnaryClientInterceptor {
-	ui.lock.Lock()
-	defer ui.lock.Unlock()
-	ui.interceptors = append(ui.interceptors, arr...)
-	return ui
+// AddGRPCInterceptor adds `arr` to the chain of interceptors with
+// `PhaseDefault` as their priority.
+func (c *unaryClientInterceptor) AddGRPCInterceptor(arr ...grpc.UnaryClientInterceptor) UnaryClientInterceptor {
+	return c.AddGRPCInterceptorWithPriority(PhaseDefault, arr...)
 }
 
-// AddInterceptor is a convenient way for adding `UnaryClientInterceptor`
-// to the chain of interceptors. It only calls the method `Interceptor`
-// for each of them and append the return value to the chain.
-func (ui *unaryClientInterceptor) AddInterceptor(arr ...UnaryClientInterceptor) UnaryClientInterceptor {
-	ui.lock.Lock()
-	defer ui.lock.Unlock()
-	for _, i := range arr {
-		ui.interceptors = append(ui.interceptors, i.Interceptor())
+// AddGRPCInterceptorWithPriority adds `arr` to the chain of interceptors with
+// `priority` as their priority.
+func (c *unaryClientInterceptor) AddGRPCInterceptorWithPriority(priority Priority, arr ...grpc.UnaryClientInterceptor) UnaryClientInterceptor {
+	c.add(priority, unaryClientInterceptors(arr))
+	return c
+}
+
+// AddInterceptor adds `arr` to the chain of interceptors by reference:
+// interceptors that are added to any of them later on are called as well.
+// Chains that are already referenced are not added again. They have
+// `PhaseDefault` as their priority.
+func (c *unaryClientInterceptor) AddInterceptor(arr ...UnaryClientInterceptor) UnaryClientInterceptor {
+	children := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		children[idx] = i
+	}
+	c.addChildren(children)
+	return c
+}
+
+// AddNamed adds `i` to the chain of interceptors under `name` with
+// `PhaseDefault` as its priority. If an interceptor has already been added
+// under `name`, it is replaced in place.
+func (c *unaryClientInterceptor) AddNamed(name string, i grpc.UnaryClientInterceptor) UnaryClientInterceptor {
+	return c.AddNamedWithPriority(name, PhaseDefault, i)
+}
+
+// AddNamedWithPriority adds `i` to the chain of interceptors under `name` with
+// `priority` as its priority. If an interceptor has already been added under
+// `name`, it is replaced in place.
+func (c *unaryClientInterceptor) AddNamedWithPriority(name string, priority Priority, i grpc.UnaryClientInterceptor) UnaryClientInterceptor {
+	c.addNamed(name, priority, i)
+	return c
+}
+
+// AddMiddleware adds the `grpc.UnaryClientInterceptor` of each given middleware to the
+// chain with the priority of the middleware, under its name if it has one.
+// Middlewares that do not define any `grpc.UnaryClientInterceptor` are ignored.
+func (c *unaryClientInterceptor) AddMiddleware(arr ...Middleware) UnaryClientInterceptor {
+	for _, m := range arr {
+		switch {
+		case m.UnaryClient == nil:
+		case m.Name == "":
+			c.AddGRPCInterceptorWithPriority(m.Priority, m.UnaryClient)
+		default:
+			c.AddNamedWithPriority(m.Name, m.Priority, m.UnaryClient)
+		}
 	}
-	return ui
+	return c
+}
+
+// AddNamedInterceptor adds `i` to the chain of interceptors by reference under
+// `name`. If `i` is already referenced, nothing is done. Otherwise, if an
+// interceptor has already been added under `name`, it is replaced in place.
+func (c *unaryClientInterceptor) AddNamedInterceptor(name string, i UnaryClientInterceptor) UnaryClientInterceptor {
+	c.addNamedChild(name, i)
+	return c
+}
+
+// Replace replaces the interceptor named `name` with `i`, keeping its name,
+// its priority and its position in the chain. It returns
+// `ErrInterceptorNotFound` if there is none.
+func (c *unaryClientInterceptor) Replace(name string, i grpc.UnaryClientInterceptor) error {
+	return c.replace(name, i)
+}
+
+// InsertBefore inserts `arr` right before the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *unaryClientInterceptor) InsertBefore(name string, arr ...grpc.UnaryClientInterceptor) error {
+	return c.insert(name, 0, unaryClientInterceptors(arr))
+}
+
+// InsertAfter inserts `arr` right after the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *unaryClientInterceptor) InsertAfter(name string, arr ...grpc.UnaryClientInterceptor) error {
+	return c.insert(name, 1, unaryClientInterceptors(arr))
 }
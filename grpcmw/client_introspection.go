@@ -0,0 +1,36 @@
+package grpcmw
+
+import "io"
+
+// WalkClientFunc is the type of the function called for each level visited
+// when walking a tree of `ClientInterceptor`. `path` is the list of indexes
+// leading to `lvl` from the level the walk started from.
+type WalkClientFunc func(path []string, lvl ClientInterceptor) error
+
+// WalkClientInterceptor calls `fn` for `lvl` and then, if it is a
+// `ClientInterceptorRegister`, recursively for each of its sublevels sorted by
+// index. It stops at the first error returned by `fn` and returns it.
+func WalkClientInterceptor(lvl ClientInterceptor, fn WalkClientFunc) error {
+	return walkLevel(clientSide, []string{}, lvl, func(path []string, lvl level) error {
+		return fn(path, lvl.(ClientInterceptor))
+	})
+}
+
+// DescribeClientInterceptor returns the description of `lvl` and of all its
+// sublevels.
+func DescribeClientInterceptor(lvl ClientInterceptor) *LevelInfo {
+	return describeLevels(clientSide, lvl)
+}
+
+// DumpClientInterceptor writes a human-readable tree view of `lvl` and of all
+// its sublevels to `w`. Like `Describe`, it shows the structure of the chains of
+// interceptors rather than the order in which they are called.
+func DumpClientInterceptor(w io.Writer, lvl ClientInterceptor) error {
+	return DescribeClientInterceptor(lvl).WriteText(w)
+}
+
+// DumpClientInterceptorJSON writes the JSON form of the description of `lvl`
+// and of all its sublevels to `w`.
+func DumpClientInterceptorJSON(w io.Writer, lvl ClientInterceptor) error {
+	return DescribeClientInterceptor(lvl).WriteJSON(w)
+}
@@ -1,10 +1,6 @@
 package grpcmw
 
-import (
-	"sync"
-
-	"google.golang.org/grpc"
-)
+import "google.golang.org/grpc"
 
 // ClientInterceptor represents a client interceptor that uses both
 // `UnaryClientInterceptor` and `StreamClientInterceptor` and that can be
@@ -15,6 +11,14 @@ type ClientInterceptor interface {
 	// AddUnaryInterceptor is a convenient way for adding `UnaryClientInterceptor`
 	// to the chain of unary interceptors.
 	AddUnaryInterceptor(i ...UnaryClientInterceptor) ClientInterceptor
+	// AddGRPCUnaryInterceptorWithPriority adds given unary interceptors to the
+	// chain with `priority` as their priority.
+	AddGRPCUnaryInterceptorWithPriority(priority Priority, i ...grpc.UnaryClientInterceptor) ClientInterceptor
+	// AddNamedGRPCUnaryInterceptor adds the given unary interceptor to the
+	// chain under `name`.
+	AddNamedGRPCUnaryInterceptor(name string, i grpc.UnaryClientInterceptor) ClientInterceptor
+	// ReplaceGRPCUnaryInterceptor replaces the unary interceptor named `name`.
+	ReplaceGRPCUnaryInterceptor(name string, i grpc.UnaryClientInterceptor) error
 	// UnaryClientInterceptor returns the chain of unary interceptors.
 	UnaryClientInterceptor() UnaryClientInterceptor
 	// AddGRPCStreamInterceptor adds given stream interceptors to the chain.
@@ -22,12 +26,37 @@ type ClientInterceptor interface {
 	// AddStreamInterceptor is a convenient way for adding
 	// `StreamClientInterceptor` to the chain of stream interceptors.
 	AddStreamInterceptor(i ...StreamClientInterceptor) ClientInterceptor
+	// AddGRPCStreamInterceptorWithPriority adds given stream interceptors to the
+	// chain with `priority` as their priority.
+	AddGRPCStreamInterceptorWithPriority(priority Priority, i ...grpc.StreamClientInterceptor) ClientInterceptor
+	// AddNamedGRPCStreamInterceptor adds the given stream interceptor to the
+	// chain under `name`.
+	AddNamedGRPCStreamInterceptor(name string, i grpc.StreamClientInterceptor) ClientInterceptor
+	// ReplaceGRPCStreamInterceptor replaces the stream interceptor named `name`.
+	ReplaceGRPCStreamInterceptor(name string, i grpc.StreamClientInterceptor) error
 	// StreamClientInterceptor returns the chain of stream interceptors.
 	StreamClientInterceptor() StreamClientInterceptor
-	// Merge merges the given interceptors with the current interceptor.
+	// RemoveInterceptor removes the unary and stream interceptors named `name`.
+	RemoveInterceptor(name string) error
+	// AddMiddleware adds the client interceptors of the given middlewares to
+	// the chains of unary and stream interceptors.
+	AddMiddleware(m ...Middleware) ClientInterceptor
+	// Merge merges the given interceptors with the current interceptor. They
+	// are kept by reference.
 	Merge(i ...ClientInterceptor) ClientInterceptor
 	// Index returns the index of the `ClientInterceptor`.
 	Index() string
+	// Exclude excludes the interceptors and chains named `names` from the
+	// parent levels of the interceptor and from the levels bound to patterns
+	// when a route goes through it.
+	Exclude(names ...string) ClientInterceptor
+	// Excluded returns the names excluded by the interceptor.
+	Excluded() []string
+	// Freeze makes the interceptor, its chains of interceptors and its
+	// sublevels immutable (see `FreezeMode`).
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the interceptor has been frozen.
+	Frozen() bool
 }
 
 // ClientInterceptorRegister represents a register of `ClientInterceptor`,
@@ -36,22 +65,35 @@ type ClientInterceptor interface {
 type ClientInterceptorRegister interface {
 	ClientInterceptor
 	// Register registers `level` at the index returned by its method `Index`.
-	Register(level ClientInterceptor)
+	// It fails with `ErrFrozen` if the register has been frozen.
+	Register(level ClientInterceptor) error
+	// Unregister removes the level registered at the index `key`. It returns
+	// `ErrInterceptorNotFound` if there is no such level and `ErrFrozen` if the
+	// register has been frozen.
+	Unregister(key string) error
+	// Keys returns the sorted indexes of the registered levels.
+	Keys() []string
+	// Reset removes all the registered levels. It fails with `ErrFrozen` if
+	// the register has been frozen.
+	Reset() error
 	// Get returns the `ClientInterceptor` registered at the index `key`. If
 	// nothing is found, it returns (nil, false).
 	Get(key string) (ClientInterceptor, bool)
+	// Walk calls `fn` for the register and recursively for all its sublevels
+	// (see `WalkClientInterceptor`).
+	Walk(fn WalkClientFunc) error
 }
 
 type lowerClientInterceptor struct {
+	*levelBase
 	unaries UnaryClientInterceptor
 	streams StreamClientInterceptor
-	index   string
 }
 
+// higherClientInterceptorLevel holds its sublevels in a `levelTree`.
 type higherClientInterceptorLevel struct {
 	ClientInterceptor
-	sublevels map[string]ClientInterceptor
-	lock      *sync.RWMutex
+	tree *levelTree
 }
 
 // NewClientInterceptor initializes a new `ClientInterceptor` with `index`
@@ -60,17 +102,12 @@ type higherClientInterceptorLevel struct {
 // This implementation is thread-safe.
 func NewClientInterceptor(index string) ClientInterceptor {
 	return &lowerClientInterceptor{
-		unaries: NewUnaryClientInterceptor(),
-		streams: NewStreamClientInterceptor(),
-		index:   index,
+		levelBase: newLevelBase(index),
+		unaries:   NewUnaryClientInterceptor(),
+		streams:   NewStreamClientInterceptor(),
 	}
 }
 
-// Index returns the index of the `ClientInterceptor`.
-func (l lowerClientInterceptor) Index() string {
-	return l.index
-}
-
 // AddGRPCUnaryInterceptor calls `AddGRPCInterceptor` of the underlying
 // `UnaryClientInterceptor`. It returns the current instance of
 // `ClientInterceptor` to allow chaining.
@@ -80,13 +117,35 @@ func (l *lowerClientInterceptor) AddGRPCUnaryInterceptor(arr ...grpc.UnaryClient
 }
 
 // AddUnaryInterceptor calls `AddInterceptor` of the underlying
-// `UnaryClientInterceptor`. It returns the current instance of
+// `UnaryClientInterceptor`, which keeps `arr` by reference. It returns the current instance of
 // `ClientInterceptor` to allow chaining.
 func (l *lowerClientInterceptor) AddUnaryInterceptor(arr ...UnaryClientInterceptor) ClientInterceptor {
 	l.unaries.AddInterceptor(arr...)
 	return l
 }
 
+// AddGRPCUnaryInterceptorWithPriority calls `AddGRPCInterceptorWithPriority` of
+// the underlying `UnaryClientInterceptor`. It returns the current instance of
+// `ClientInterceptor` to allow chaining.
+func (l *lowerClientInterceptor) AddGRPCUnaryInterceptorWithPriority(priority Priority, arr ...grpc.UnaryClientInterceptor) ClientInterceptor {
+	l.unaries.AddGRPCInterceptorWithPriority(priority, arr...)
+	return l
+}
+
+// AddNamedGRPCUnaryInterceptor calls `AddNamed` of the underlying
+// `UnaryClientInterceptor`. It returns the current instance of
+// `ClientInterceptor` to allow chaining.
+func (l *lowerClientInterceptor) AddNamedGRPCUnaryInterceptor(name string, i grpc.UnaryClientInterceptor) ClientInterceptor {
+	l.unaries.AddNamed(name, i)
+	return l
+}
+
+// ReplaceGRPCUnaryInterceptor calls `Replace` of the underlying
+// `UnaryClientInterceptor`.
+func (l *lowerClientInterceptor) ReplaceGRPCUnaryInterceptor(name string, i grpc.UnaryClientInterceptor) error {
+	return l.unaries.Replace(name, i)
+}
+
 // UnaryClientInterceptor returns the underlying instance of
 // `UnaryClientInterceptor`.
 func (l *lowerClientInterceptor) UnaryClientInterceptor() UnaryClientInterceptor {
@@ -101,25 +160,93 @@ func (l *lowerClientInterceptor) AddGRPCStreamInterceptor(arr ...grpc.StreamClie
 	return l
 }
 
-// AddStreamInterceptor calls `AddGRPCInterceptor` of the underlying
-// `StreamClientInterceptor`. It returns the current instance of
+// AddStreamInterceptor calls `AddInterceptor` of the underlying
+// `StreamClientInterceptor`, which keeps `arr` by reference. It returns the current instance of
 // `ClientInterceptor` to allow chaining.
 func (l *lowerClientInterceptor) AddStreamInterceptor(arr ...StreamClientInterceptor) ClientInterceptor {
 	l.streams.AddInterceptor(arr...)
 	return l
 }
 
+// AddGRPCStreamInterceptorWithPriority calls `AddGRPCInterceptorWithPriority` of
+// the underlying `StreamClientInterceptor`. It returns the current instance of
+// `ClientInterceptor` to allow chaining.
+func (l *lowerClientInterceptor) AddGRPCStreamInterceptorWithPriority(priority Priority, arr ...grpc.StreamClientInterceptor) ClientInterceptor {
+	l.streams.AddGRPCInterceptorWithPriority(priority, arr...)
+	return l
+}
+
+// AddNamedGRPCStreamInterceptor calls `AddNamed` of the underlying
+// `StreamClientInterceptor`. It returns the current instance of
+// `ClientInterceptor` to allow chaining.
+func (l *lowerClientInterceptor) AddNamedGRPCStreamInterceptor(name string, i grpc.StreamClientInterceptor) ClientInterceptor {
+	l.streams.AddNamed(name, i)
+	return l
+}
+
+// ReplaceGRPCStreamInterceptor calls `Replace` of the underlying
+// `StreamClientInterceptor`.
+func (l *lowerClientInterceptor) ReplaceGRPCStreamInterceptor(name string, i grpc.StreamClientInterceptor) error {
+	return l.streams.Replace(name, i)
+}
+
 // StreamClientInterceptor returns the underlying instance of
 // `StreamClientInterceptor`.
 func (l *lowerClientInterceptor) StreamClientInterceptor() StreamClientInterceptor {
 	return l.streams
 }
 
-// Merge merges the given interceptors with the current interceptor.
+// RemoveInterceptor removes the interceptors named `name` from both the
+// underlying `UnaryClientInterceptor` and `StreamClientInterceptor`. It returns
+// `ErrInterceptorNotFound` only if neither of them had such an interceptor.
+func (l *lowerClientInterceptor) RemoveInterceptor(name string) error {
+	return removeError(l.unaries.Remove(name), l.streams.Remove(name))
+}
+
+// Freeze freezes the underlying `UnaryClientInterceptor` and
+// `StreamClientInterceptor`, as well as the names excluded by the interceptor.
+func (l *lowerClientInterceptor) Freeze(mode FreezeMode) {
+	l.freeze(mode)
+	l.unaries.Freeze(mode)
+	l.streams.Freeze(mode)
+}
+
+// Exclude excludes the interceptors and chains named `names` from the parent
+// levels of the interceptor, as well as from the levels bound to patterns that
+// match the route, when a route goes through it. For instance, excluding
+// "auth" at the level of the method "/grpc.health.v1.Health/Check" prevents
+// the interceptor named "auth" of the global level from being called for this
+// method. It returns the current instance of `ClientInterceptor` to allow
+// chaining.
+func (l *lowerClientInterceptor) Exclude(names ...string) ClientInterceptor {
+	l.exclude(names)
+	return l
+}
+
+// Frozen returns whether the underlying chains of interceptors have been
+// frozen.
+func (l *lowerClientInterceptor) Frozen() bool {
+	return l.unaries.Frozen() && l.streams.Frozen()
+}
+
+// AddMiddleware calls `AddMiddleware` of both the underlying
+// `UnaryClientInterceptor` and `StreamClientInterceptor`. It returns the current
+// instance of `ClientInterceptor` to allow chaining.
+func (l *lowerClientInterceptor) AddMiddleware(arr ...Middleware) ClientInterceptor {
+	l.unaries.AddMiddleware(arr...)
+	l.streams.AddMiddleware(arr...)
+	return l
+}
+
+// Merge merges the given interceptors with the current interceptor. Their
+// chains of unary and stream interceptors are added by reference under their
+// index: interceptors added to them later on are also called. Merging the same
+// interceptor twice has no effect, but interceptors that share an index are all
+// merged.
 func (l *lowerClientInterceptor) Merge(interceptors ...ClientInterceptor) ClientInterceptor {
 	for _, interceptor := range interceptors {
-		l.AddUnaryInterceptor(interceptor.UnaryClientInterceptor()).
-			AddStreamInterceptor(interceptor.StreamClientInterceptor())
+		coreOf(l.unaries).merge(interceptor.Index(), interceptor.UnaryClientInterceptor())
+		coreOf(l.streams).merge(interceptor.Index(), interceptor.StreamClientInterceptor())
 	}
 	return l
 }
@@ -130,24 +257,67 @@ func (l *lowerClientInterceptor) Merge(interceptors ...ClientInterceptor) Client
 func NewClientInterceptorRegister(index string) ClientInterceptorRegister {
 	return &higherClientInterceptorLevel{
 		ClientInterceptor: NewClientInterceptor(index),
-		sublevels:         make(map[string]ClientInterceptor),
-		lock:              &sync.RWMutex{},
+		tree:              newLevelTree(),
 	}
 }
 
 // Get returns the `ClientInterceptor` registered at the index `key`. If nothing
 // is found, it returns (nil, false).
-func (l higherClientInterceptorLevel) Get(key string) (interceptor ClientInterceptor, exists bool) {
-	l.lock.RLock()
-	defer l.lock.RUnlock()
-	interceptor, exists = l.sublevels[key]
-	return
+func (l *higherClientInterceptorLevel) Get(key string) (ClientInterceptor, bool) {
+	sub, exists := l.tree.get(key)
+	if !exists {
+		return nil, false
+	}
+	return sub.(ClientInterceptor), true
 }
 
 // Register registers `level` at the index returned by its method `Index`.
 // It overwrites any interceptor that has already been registered at this index.
-func (l *higherClientInterceptorLevel) Register(level ClientInterceptor) {
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	l.sublevels[level.Index()] = level
+// It fails with `ErrFrozen` if the register has been frozen.
+func (l *higherClientInterceptorLevel) Register(level ClientInterceptor) error {
+	return l.tree.register(level)
+}
+
+// Unregister removes the level registered at the index `key`. It returns
+// `ErrInterceptorNotFound` if there is no such level and `ErrFrozen` if the
+// register has been frozen.
+func (l *higherClientInterceptorLevel) Unregister(key string) error {
+	return l.tree.unregister(key)
+}
+
+// Keys returns the sorted indexes of the registered levels.
+func (l *higherClientInterceptorLevel) Keys() []string {
+	return l.tree.keys()
+}
+
+// Reset removes all the registered levels. The interceptors of the register
+// itself are kept. It fails with `ErrFrozen` if the register has been frozen.
+func (l *higherClientInterceptorLevel) Reset() error {
+	return l.tree.reset()
+}
+
+// Freeze makes the register immutable, as well as its chains of interceptors
+// and all its sublevels. Registering a new level afterwards either fails with
+// `ErrFrozen` or panics, depending on `mode`.
+func (l *higherClientInterceptorLevel) Freeze(mode FreezeMode) {
+	if l.tree.freeze(mode) {
+		l.ClientInterceptor.Freeze(mode)
+	}
+}
+
+// Frozen returns whether the register has been frozen.
+func (l *higherClientInterceptorLevel) Frozen() bool {
+	return l.tree.isFrozen()
+}
+
+// levelTree returns the sublevels of the register.
+func (l *higherClientInterceptorLevel) levelTree() *levelTree {
+	return l.tree
+}
+
+// Walk calls `fn` for the register and then recursively for each of its
+// sublevels sorted by index. It stops at the first error returned by `fn` and
+// returns it.
+func (l *higherClientInterceptorLevel) Walk(fn WalkClientFunc) error {
+	return WalkClientInterceptor(l, fn)
 }
@@ -1,8 +1,7 @@
 package grpcmw
 
 import (
-	"errors"
-	"fmt"
+	"sync/atomic"
 
 	"golang.org/x/net/context"
 
@@ -15,18 +14,91 @@ import (
 type ClientRouter interface {
 	// GetRegister returns the interceptor register of the router.
 	GetRegister() ClientInterceptorRegister
-	// SetRegister sets the interceptor register of the router.
-	SetRegister(reg ClientInterceptorRegister)
+	// SetRegister sets the interceptor register of the router. It fails with
+	// `ErrFrozen` if the router has been frozen.
+	SetRegister(reg ClientInterceptorRegister) error
+	// Reload sets up a new router with `build` and sets its interceptor
+	// register and its patterns as those of the router, only if it succeeds
+	// and the chains of every route it defines can be compiled.
+	Reload(build func(staging ClientRouter) error) error
 	// UnaryResolver returns a `grpc.UnaryClientInterceptor` that uses the
 	// appropriate chain of interceptors with the given unary gRPC request.
 	UnaryResolver() grpc.UnaryClientInterceptor
 	// StreamResolver returns a `grpc.StreamClientInterceptor` that uses the
 	// appropriate chain of interceptors with the given stream gRPC request.
 	StreamResolver() grpc.StreamClientInterceptor
+	// DialOptions returns the options that install both `UnaryResolver` and
+	// `StreamResolver` on a `grpc.ClientConn`.
+	DialOptions() []grpc.DialOption
+	// CheckWiring checks that the resolvers of the router needed by its
+	// interceptors have been installed.
+	CheckWiring(mode WiringMode) error
+	// Resolve returns the description of the interceptors that are called for
+	// the given route, applying the policy for unknown routes, without calling
+	// any of them.
+	Resolve(route string) (*RouteInfo, error)
+	// AddPattern adds `lvl` as a level that applies to every route matching
+	// `pattern` (e.g. "/pkg.*/Get*").
+	AddPattern(pattern string, lvl ClientInterceptor) error
+	// RegisterPackage returns the register of the level of the protobuf
+	// package `pkg`, registering it if needed. `created` is true the first
+	// time the level of `pkg` is requested, unless it has been registered
+	// otherwise.
+	RegisterPackage(pkg string) (reg ClientInterceptorRegister, created bool)
+	// Freeze freezes the interceptor register of the router and compiles the
+	// chains of interceptors of every route it defines once and for all.
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the router has been frozen.
+	Frozen() bool
 }
 
+// clientRouter is the `clientRouter` implementation of the shared router (see
+// `router`), which only converts levels and interceptors from and to their
+// client types.
 type clientRouter struct {
-	interceptors ClientInterceptorRegister
+	*router
+}
+
+// clientSide describes the client levels to the shared router.
+var clientSide = &side{
+	registerType: "grpcmw.ClientInterceptorRegister",
+	unary:        unaryClientKind,
+	stream:       streamClientKind,
+	chains: func(lvl level) (interface{}, interface{}) {
+		client := lvl.(ClientInterceptor)
+		return client.UnaryClientInterceptor(), client.StreamClientInterceptor()
+	},
+	isRegister: func(lvl level) bool {
+		_, ok := lvl.(ClientInterceptorRegister)
+		return ok
+	},
+	get: func(reg level, key string) (level, bool) {
+		sub, exists := reg.(ClientInterceptorRegister).Get(key)
+		return sub, exists
+	},
+	register: func(reg, sub level) error {
+		return reg.(ClientInterceptorRegister).Register(sub.(ClientInterceptor))
+	},
+	newRegister: func(index string) level {
+		return NewClientInterceptorRegister(index)
+	},
+	walk: func(reg level, fn func(path []string, lvl level) error) error {
+		return reg.(ClientInterceptorRegister).Walk(func(path []string, lvl ClientInterceptor) error {
+			return fn(path, lvl)
+		})
+	},
+	reject: func() (interface{}, interface{}) {
+		unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return grpc.Errorf(codes.Unimplemented, "Unknown route %s", method)
+		}
+		stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return nil, grpc.Errorf(codes.Unimplemented, "Unknown route %s", method)
+		}
+		return grpc.UnaryClientInterceptor(unary), grpc.StreamClientInterceptor(stream)
+	},
+	fallback: func(m Middleware) (interface{}, interface{}) {
+		return NewUnaryClientInterceptor().AddMiddleware(m), NewStreamClientInterceptor().AddMiddleware(m)
+	},
 }
 
 // NewClientRouter initializes a `ClientRouter`.
@@ -43,87 +115,138 @@ type clientRouter struct {
 //     a method from the corresponding service.
 //   - the method level: these are the interceptors called at each request to
 //     the specific method.
-func NewClientRouter() ClientRouter {
+//
+// How requests to unknown routes are handled can be set with
+// `WithUnknownRoutePolicy`.
+//
+// With `WithNestedPackages`, each segment of the package is a level of its own
+// instead of a single package level.
+//
+// Levels can also be bound to route patterns (see `AddPattern`).
+//
+// The chain of interceptors of a known route is compiled the first time the
+// route is requested and cached until any level or chain of interceptors is
+// modified.
+func NewClientRouter(opts ...RouterOption) ClientRouter {
 	return &clientRouter{
-		interceptors: NewClientInterceptorRegister("global"),
+		router: newRouter(clientSide, NewClientInterceptorRegister("global"), newRouterOptions(opts)),
 	}
 }
 
-func resolveClientInterceptorRec(pathTokens []string, lvl ClientInterceptor, cb func(lvl ClientInterceptor), force bool) (ClientInterceptor, error) {
-	if cb != nil {
-		cb(lvl)
-	}
-	if len(pathTokens) == 0 || len(pathTokens[0]) == 0 {
-		return lvl, nil
-	}
-	reg, ok := lvl.(ClientInterceptorRegister)
-	if !ok {
-		return nil, fmt.Errorf("Level %s does not implement grpcmw.ClientInterceptorRegister", lvl.Index())
-	}
-	sub, exists := reg.Get(pathTokens[0])
-	if !exists {
-		if force {
-			if len(pathTokens) == 1 {
-				sub = NewClientInterceptor(pathTokens[0])
-			} else {
-				sub = NewClientInterceptorRegister(pathTokens[0])
-			}
-			reg.Register(sub)
-		} else {
-			return nil, nil
-		}
-	}
-	return resolveClientInterceptorRec(pathTokens[1:], sub, cb, force)
-}
-
-func resolveClientInterceptor(route string, lvl ClientInterceptor, cb func(lvl ClientInterceptor), force bool) (ClientInterceptor, error) {
-	// TODO: Find a more efficient way to resolve the route
-	matchs := routeRegexp.FindStringSubmatch(route)
-	if len(matchs) == 0 {
-		return nil, errors.New("Invalid route")
-	}
-	return resolveClientInterceptorRec(matchs[1:], lvl, cb, force)
-}
-
 // UnaryResolver returns a `grpc.UnaryClientInterceptor` that uses the
 // appropriate chain of interceptors with the given gRPC request.
 func (r *clientRouter) UnaryResolver() grpc.UnaryClientInterceptor {
+	atomic.StoreInt32(&r.unaryWired, 1)
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		// TODO: Find a more efficient way to chain the interceptors
-		interceptor := NewUnaryClientInterceptor()
-		_, err := resolveClientInterceptor(method, r.interceptors, func(lvl ClientInterceptor) {
-			interceptor.AddInterceptor(lvl.UnaryClientInterceptor())
-		}, false)
+		route, err := r.resolve(method)
 		if err != nil {
 			return grpc.Errorf(codes.Internal, err.Error())
 		}
-		return interceptor.Interceptor()(ctx, method, req, reply, cc, invoker, opts...)
+		return route.unary.(grpc.UnaryClientInterceptor)(ctx, method, req, reply, cc, invoker, opts...)
 	}
 }
 
 // StreamResolver returns a `grpc.StreamClientInterceptor` that uses the
 // appropriate chain of interceptors with the given stream gRPC request.
 func (r *clientRouter) StreamResolver() grpc.StreamClientInterceptor {
+	atomic.StoreInt32(&r.streamWired, 1)
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		// TODO: Find a more efficient way to chain the interceptors
-		interceptor := NewStreamClientInterceptor()
-		_, err := resolveClientInterceptor(method, r.interceptors, func(lvl ClientInterceptor) {
-			interceptor.AddInterceptor(lvl.StreamClientInterceptor())
-		}, false)
+		route, err := r.resolve(method)
 		if err != nil {
 			return nil, grpc.Errorf(codes.Internal, err.Error())
 		}
-		return interceptor.Interceptor()(ctx, desc, cc, method, streamer, opts...)
+		return route.stream.(grpc.StreamClientInterceptor)(ctx, desc, cc, method, streamer, opts...)
+	}
+}
+
+// DialOptions returns the options that install both `UnaryResolver` and
+// `StreamResolver` on a `grpc.ClientConn`, so that streaming methods are not
+// left without interceptors:
+//
+//	conn, err := grpc.Dial(target, router.DialOptions()...)
+func (r *clientRouter) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(r.UnaryResolver()),
+		grpc.WithStreamInterceptor(r.StreamResolver()),
+	}
+}
+
+// CheckWiring checks that the resolvers of the router have been requested (see
+// `UnaryResolver`, `StreamResolver` and `DialOptions`) if any of its levels has
+// interceptors of the corresponding type, including the levels bound to
+// patterns, as the methods called by a client are not known in advance.
+// Depending on `mode`, the levels whose interceptors would never be called are
+// reported by returning an error, logging it or panicking with it.
+func (r *clientRouter) CheckWiring(mode WiringMode) error {
+	unaryLevels, streamLevels := r.levelsWithInterceptors()
+	if atomic.LoadInt32(&r.unaryWired) != 0 {
+		unaryLevels = nil
 	}
+	if atomic.LoadInt32(&r.streamWired) != 0 {
+		streamLevels = nil
+	}
+	return wiringError(mode, unaryLevels, streamLevels)
 }
 
 // GetRegister returns the underlying `ClientInterceptorRegister` which is the
 // global level in the interceptor chain.
 func (r *clientRouter) GetRegister() ClientInterceptorRegister {
-	return r.interceptors
+	return r.loadState().register.(ClientInterceptorRegister)
+}
+
+// SetRegister sets the interceptor register of the router. The chains of
+// interceptors of the routes it defines are compiled before it is set, so that
+// requests never wait for them to be compiled. Requests that are being handled
+// keep on using the chains of interceptors they started with. It fails with
+// `ErrFrozen` if the router has been frozen.
+func (r *clientRouter) SetRegister(reg ClientInterceptorRegister) error {
+	return r.swap(reg, nil, false)
+}
+
+// Reload sets up `staging`, a new router with the same options and an empty
+// global level, with `build`. It then sets the interceptor register of
+// `staging` as the register of the router (see `SetRegister`) and its levels
+// bound to patterns as those of the router, so that `build` can call
+// `RegisterPackage`, `AddPattern` or the generated `RegisterClientInterceptors`
+// on it. The router is left untouched if `build` fails or if the chains of
+// interceptors of any route defined by the new register cannot be compiled
+// (e.g. a service level that is not a register). It fails with `ErrFrozen` if
+// the router has been frozen. `staging` must not be used once `build` returns.
+func (r *clientRouter) Reload(build func(staging ClientRouter) error) error {
+	return r.reload(func(staging *router) error {
+		return build(&clientRouter{router: staging})
+	})
+}
+
+// AddPattern adds `lvl` as a level that applies to every route matching
+// `pattern`, where `*` matches any sequence of characters except '/'. For
+// instance, "/company.billing.*/*" matches all the methods of the services of
+// the package "company.billing" and of its subpackages, and "/*/List*" all the
+// methods whose name starts with "List". Only the stream interceptors of a
+// level are called for streaming methods, so "/*/*" along with stream
+// interceptors only applies to streaming methods.
+//
+// The interceptors of the levels bound to patterns are called after the ones of
+// the global, package, service and method levels, in the order in which the
+// patterns have been added. They are still subject to the names excluded by
+// these levels (see `Exclude`). The pattern is compiled once, so that matching
+// a route never allocates. It fails with `ErrFrozen` if the router has been
+// frozen.
+func (r *clientRouter) AddPattern(pattern string, lvl ClientInterceptor) error {
+	return r.addPattern(pattern, lvl)
 }
 
-// SetRegister sets the interceptor register of the router.
-func (r *clientRouter) SetRegister(reg ClientInterceptorRegister) {
-	r.interceptors = reg
+// RegisterPackage returns the register of the level of the protobuf package
+// `pkg`, registering it along with its parent levels if needed. `created` is
+// true the first time the level of `pkg` is requested, including when it has
+// only been registered as the parent of a subpackage so far, so that the
+// caller knows it has to set it up. It panics if a level that is not a
+// `ClientInterceptorRegister` is already registered on the path of `pkg`. If the
+// register of the router has been frozen, the level is not registered: the
+// modification is ignored as for any frozen register (see `FreezeMode`), and
+// the returned register is detached from the router and never reported as
+// created.
+func (r *clientRouter) RegisterPackage(pkg string) (reg ClientInterceptorRegister, created bool) {
+	lvl, created := r.registerPackage(pkg)
+	return lvl.(ClientInterceptorRegister), created
 }
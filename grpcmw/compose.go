@@ -0,0 +1,61 @@
+package grpcmw
+
+import "google.golang.org/grpc"
+
+// ComposeServerRouters returns a level whose chains call the resolvers of
+// `routers`, in the given order, so that several routers can be installed on a
+// single `grpc.Server`, which only accepts one unary and one stream
+// interceptor. Raw interceptors can be added to the returned level as well, and
+// are called before or after the routers depending on their priority and on
+// the order in which they are added (see `Priority`). The result is meant to be
+// installed with `ServerOptions`.
+//
+// Each router is called for every request, so routers that do not define every
+// service should handle unknown routes with `UnknownRoutePassThrough`.
+func ComposeServerRouters(routers ...ServerRouter) ServerInterceptor {
+	lvl := NewServerInterceptor("routers")
+	for _, r := range routers {
+		lvl.AddGRPCUnaryInterceptor(r.UnaryResolver())
+		lvl.AddGRPCStreamInterceptor(r.StreamResolver())
+	}
+	return lvl
+}
+
+// ComposeClientRouters returns a level whose chains call the resolvers of
+// `routers`, in the given order, so that several routers can be installed on a
+// single `grpc.ClientConn`, which only accepts one unary and one stream
+// interceptor. Raw interceptors can be added to the returned level as well, and
+// are called before or after the routers depending on their priority and on
+// the order in which they are added (see `Priority`). The result is meant to be
+// installed with `DialOptions`.
+//
+// Each router is called for every request, so routers that do not define every
+// service should handle unknown routes with `UnknownRoutePassThrough`.
+func ComposeClientRouters(routers ...ClientRouter) ClientInterceptor {
+	lvl := NewClientInterceptor("routers")
+	for _, r := range routers {
+		lvl.AddGRPCUnaryInterceptor(r.UnaryResolver())
+		lvl.AddGRPCStreamInterceptor(r.StreamResolver())
+	}
+	return lvl
+}
+
+// ServerOptions returns the options that install both the unary and the stream
+// chains of `lvl` on a `grpc.Server`. Interceptors added to `lvl` afterwards
+// are called as well.
+func ServerOptions(lvl ServerInterceptor) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(lvl.UnaryServerInterceptor().Interceptor()),
+		grpc.StreamInterceptor(lvl.StreamServerInterceptor().Interceptor()),
+	}
+}
+
+// DialOptions returns the options that install both the unary and the stream
+// chains of `lvl` on a `grpc.ClientConn`. Interceptors added to `lvl`
+// afterwards are called as well.
+func DialOptions(lvl ClientInterceptor) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(lvl.UnaryClientInterceptor().Interceptor()),
+		grpc.WithStreamInterceptor(lvl.StreamClientInterceptor().Interceptor()),
+	}
+}
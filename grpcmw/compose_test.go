@@ -0,0 +1,38 @@
+package grpcmw
+
+import "testing"
+
+func TestComposeServerRouters(t *testing.T) {
+	var calls []string
+	newRouter := func(pkg string) ServerRouter {
+		r := NewServerRouter(WithUnknownRoutePolicy(UnknownRoutePassThrough))
+		r.GetRegister().AddGRPCUnaryInterceptor(recordServerUnary(&calls, pkg+" global"))
+		reg, _ := r.RegisterPackage(pkg)
+		reg.AddGRPCUnaryInterceptor(recordServerUnary(&calls, pkg))
+		reg.Register(NewServerInterceptorRegister("Service"))
+		return r
+	}
+	billing, shipping := newRouter("billing"), newRouter("shipping")
+	routers := ComposeServerRouters(billing, shipping)
+	routers.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "after"))
+	routers.AddGRPCUnaryInterceptorWithPriority(PhaseObservability, recordServerUnary(&calls, "metrics"))
+	resolver := routers.UnaryServerInterceptor().Interceptor()
+
+	for route, want := range map[string][]string{
+		"/billing.Service/Method":  {"metrics", "billing global", "billing", "after"},
+		"/shipping.Service/Method": {"metrics", "shipping global", "shipping", "after"},
+		"/other.Service/Method":    {"metrics", "after"},
+	} {
+		if got := callServerUnary(t, resolver, &calls, route); !equalStrings(got, want) {
+			t.Errorf("%s: called %q, want %q", route, got, want)
+		}
+	}
+
+	// Interceptors added to the routers afterwards are called as well.
+	shippingPkg, _ := shipping.RegisterPackage("shipping")
+	shippingPkg.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "added"))
+	want := []string{"metrics", "shipping global", "shipping", "added", "after"}
+	if got := callServerUnary(t, resolver, &calls, "/shipping.Service/Method"); !equalStrings(got, want) {
+		t.Errorf("called %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package grpcmw
+
+import (
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Condition reports whether conditional interceptors (see `When`) have to be
+// called for a request, given its context. It is evaluated on each request.
+type Condition func(ctx context.Context) bool
+
+// When returns a copy of `m` whose interceptors are only called for the
+// requests that satisfy `cond`. The requests that do not are passed as is to
+// the next interceptor of the chain, so that a chain can branch without each
+// interceptor checking `cond` on its own. Conditions on server interceptors are
+// evaluated with the context of the request, and with the context of the
+// stream for stream interceptors.
+func When(cond Condition, m Middleware) Middleware {
+	if i := m.UnaryServer; i != nil {
+		m.UnaryServer = func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if !cond(ctx) {
+				return handler(ctx, req)
+			}
+			return i(ctx, req, info, handler)
+		}
+	}
+	if i := m.StreamServer; i != nil {
+		m.StreamServer = func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if !cond(ss.Context()) {
+				return handler(srv, ss)
+			}
+			return i(srv, ss, info, handler)
+		}
+	}
+	if i := m.UnaryClient; i != nil {
+		m.UnaryClient = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if !cond(ctx) {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			return i(ctx, method, req, reply, cc, invoker, opts...)
+		}
+	}
+	if i := m.StreamClient; i != nil {
+		m.StreamClient = func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if !cond(ctx) {
+				return streamer(ctx, desc, cc, method, opts...)
+			}
+			return i(ctx, desc, cc, method, streamer, opts...)
+		}
+	}
+	return m
+}
+
+// IncomingMetadata returns a `Condition` satisfied by the requests whose
+// incoming metadata holds `key` with any of `values`, or with any value if none
+// is given. It is meant for server interceptors.
+func IncomingMetadata(key string, values ...string) Condition {
+	key = strings.ToLower(key)
+	return func(ctx context.Context) bool {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return hasMetadata(md, key, values)
+	}
+}
+
+// OutgoingMetadata returns a `Condition` satisfied by the requests whose
+// outgoing metadata holds `key` with any of `values`, or with any value if none
+// is given. It is meant for client interceptors.
+func OutgoingMetadata(key string, values ...string) Condition {
+	key = strings.ToLower(key)
+	return func(ctx context.Context) bool {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return hasMetadata(md, key, values)
+	}
+}
+
+// hasMetadata returns whether `md` holds `key` with any of `values`, or with
+// any value if `values` is empty. `key` must be in lower case, as the keys of
+// `md` are.
+func hasMetadata(md metadata.MD, key string, values []string) bool {
+	found, exists := md[key]
+	if !exists {
+		return false
+	}
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		for _, candidate := range found {
+			if candidate == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FromPeer returns a `Condition` satisfied by the requests whose peer (e.g. the
+// address or the authentication information of the client) satisfies `fn`. The
+// peer is only known by server interceptors.
+func FromPeer(fn func(p *peer.Peer) bool) Condition {
+	return func(ctx context.Context) bool {
+		p, ok := peer.FromContext(ctx)
+		return ok && fn(p)
+	}
+}
+
+// Not returns a `Condition` satisfied by the requests that do not satisfy
+// `cond`.
+func Not(cond Condition) Condition {
+	return func(ctx context.Context) bool {
+		return !cond(ctx)
+	}
+}
+
+// All returns a `Condition` satisfied by the requests that satisfy all of
+// `conds`.
+func All(conds ...Condition) Condition {
+	return func(ctx context.Context) bool {
+		for _, cond := range conds {
+			if !cond(ctx) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any returns a `Condition` satisfied by the requests that satisfy any of
+// `conds`.
+func Any(conds ...Condition) Condition {
+	return func(ctx context.Context) bool {
+		for _, cond := range conds {
+			if cond(ctx) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,51 @@
+package grpcmw
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWhen(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.Register(NewServerInterceptorRegister("Service"))
+	r.GetRegister().
+		AddGRPCUnaryInterceptor(recordServerUnary(&calls, "before")).
+		AddMiddleware(When(IncomingMetadata("X-Debug", "1", "true"), Middleware{
+			Name:        "debug",
+			UnaryServer: recordServerUnary(&calls, "debug"),
+		})).
+		AddMiddleware(When(Not(IncomingMetadata("x-internal")), Middleware{
+			UnaryServer: recordServerUnary(&calls, "external"),
+		})).
+		AddGRPCUnaryInterceptor(recordServerUnary(&calls, "after"))
+
+	tests := []struct {
+		md   metadata.MD
+		want []string
+	}{
+		{md: nil, want: []string{"before", "external", "after"}},
+		{md: metadata.Pairs("x-debug", "true"), want: []string{"before", "debug", "external", "after"}},
+		{md: metadata.Pairs("x-debug", "0"), want: []string{"before", "external", "after"}},
+		{md: metadata.Pairs("x-debug", "1", "x-internal", ""), want: []string{"before", "debug", "after"}},
+	}
+	for _, test := range tests {
+		calls = nil
+		ctx := metadata.NewIncomingContext(context.Background(), test.md)
+		_, err := r.UnaryResolver()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls = append(calls, "handler")
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", test.md, err)
+		}
+		want := append(test.want, "handler")
+		if !equalStrings(calls, want) {
+			t.Errorf("%v: called %q, want %q", test.md, calls, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+)
+
+// ClientRouter builds a `grpcmw.ClientRouter` from the configuration. It fails
+// if an interceptor is neither registered in the registry (see `WithRegistry`)
+// nor created by a factory of the registry.
+func (c *Config) ClientRouter(opts ...Option) (grpcmw.ClientRouter, error) {
+	o := newOptions(opts)
+	r := grpcmw.NewClientRouter(o.router...)
+	if err := c.setUpClientRouter(r, o); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReloadClientRouter replaces the levels of `r`, including the ones bound to
+// patterns, with those of the configuration (see `grpcmw.ClientRouter.Reload`).
+// `r` is left untouched if the configuration is invalid. The options of `r` are
+// kept: `WithRouterOptions` is ignored.
+func (c *Config) ReloadClientRouter(r grpcmw.ClientRouter, opts ...Option) error {
+	o := newOptions(opts)
+	return r.Reload(func(staging grpcmw.ClientRouter) error {
+		return c.setUpClientRouter(staging, o)
+	})
+}
+
+// setUpClientRouter sets the levels of the configuration up in `r`.
+func (c *Config) setUpClientRouter(r grpcmw.ClientRouter, o *options) error {
+	patterns := make(map[string]grpcmw.ClientInterceptor)
+	for idx := range c.Levels {
+		l := &c.Levels[idx]
+		if err := l.validate(); err != nil {
+			return err
+		}
+		interceptors := make([]grpcmw.ClientInterceptor, 0, len(l.Interceptors))
+		for _, intcp := range l.Interceptors {
+			resolved, err := o.clientInterceptor(intcp, l)
+			if err != nil {
+				return fmt.Errorf("Level %s: %v", l, err)
+			}
+			interceptors = append(interceptors, resolved)
+		}
+		lvl, err := clientLevel(r, l, patterns)
+		if err != nil {
+			return fmt.Errorf("Level %s: %v", l, err)
+		}
+		lvl.Merge(interceptors...)
+		lvl.Exclude(l.Exclude...)
+	}
+	return nil
+}
+
+// clientInterceptor returns the client interceptors referenced by `intcp` for
+// the level `l`. Unlike `registry.Registry.NewClientInterceptor`, it fails if
+// nothing is registered at the name of `intcp`.
+func (o *options) clientInterceptor(intcp Interceptor, l *Level) (grpcmw.ClientInterceptor, error) {
+	if _, ok := o.registry.LookupClientFactory(intcp.Name); !ok {
+		if _, ok := o.registry.LookupClientInterceptor(intcp.Name); !ok {
+			return nil, fmt.Errorf("Interceptor %s is not registered", intcp.Name)
+		}
+	}
+	return o.registry.NewClientInterceptor(intcp.Name, intcp.Params, registry.RouteInfo{
+		Package: l.Package,
+		Service: l.Service,
+		Method:  l.Method,
+		Pattern: l.Pattern,
+	})
+}
+
+// clientLevel returns the level of `r` described by `l`, registering it if
+// needed. Pattern levels are shared through `patterns`.
+func clientLevel(r grpcmw.ClientRouter, l *Level, patterns map[string]grpcmw.ClientInterceptor) (grpcmw.ClientInterceptor, error) {
+	if l.Pattern != "" {
+		lvl, exists := patterns[l.Pattern]
+		if !exists {
+			lvl = grpcmw.NewClientInterceptor(l.Pattern)
+			if err := r.AddPattern(l.Pattern, lvl); err != nil {
+				return nil, err
+			}
+			patterns[l.Pattern] = lvl
+		}
+		return lvl, nil
+	}
+	if l.Package == "" && l.Service == "" {
+		return r.GetRegister(), nil
+	}
+	var lvl grpcmw.ClientInterceptor
+	lvl, _ = r.RegisterPackage(l.Package)
+	for _, index := range []string{l.Service, l.Method} {
+		if index == "" {
+			break
+		}
+		reg, ok := lvl.(grpcmw.ClientInterceptorRegister)
+		if !ok {
+			return nil, fmt.Errorf("Level %s does not implement grpcmw.ClientInterceptorRegister", lvl.Index())
+		}
+		sub, exists := reg.Get(index)
+		if !exists {
+			sub = grpcmw.NewClientInterceptorRegister(index)
+			if err := reg.Register(sub); err != nil {
+				return nil, fmt.Errorf("Level %s: %v", index, err)
+			}
+		}
+		lvl = sub
+	}
+	return lvl, nil
+}
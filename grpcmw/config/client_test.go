@@ -0,0 +1,43 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestClientRouter(t *testing.T) {
+	var calls []string
+	reg := registry.New()
+	for _, name := range []string{"tracing", "retry"} {
+		name := name
+		reg.SetClientInterceptor(name, grpcmw.NewClientInterceptor(name).AddGRPCUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			calls = append(calls, name)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}))
+	}
+	cfg, err := LoadYAML(strings.NewReader(`
+levels:
+  - interceptors: [tracing]
+  - service: Health
+    interceptors: [retry]
+`))
+	if err != nil {
+		t.Fatalf("LoadYAML() = %v", err)
+	}
+	r, err := cfg.ClientRouter(WithRegistry(reg))
+	if err != nil {
+		t.Fatalf("ClientRouter() = %v", err)
+	}
+	err = r.UnaryResolver()(context.Background(), "/Health/Check", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(calls, []string{"tracing", "retry"}) {
+		t.Errorf("/Health/Check called %v (%v), want [tracing retry]", calls, err)
+	}
+}
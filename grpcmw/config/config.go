@@ -0,0 +1,183 @@
+// Package config builds routers from a declarative description of their
+// levels, so that the interceptors used by each route can be changed without
+// changing the code.
+//
+// A configuration maps levels (the global level, a package, a service, a
+// method or a route pattern) to interceptors registered in the `registry`
+// package, or created by factories with parameters:
+//
+//	{
+//	  "levels": [
+//	    {"interceptors": ["recovery"]},
+//	    {"package": "pb", "service": "SomeService", "interceptors": [
+//	      "logging",
+//	      {"name": "ratelimit", "params": {"rps": "10"}}
+//	    ]},
+//	    {"package": "pb", "service": "SomeService", "method": "Health", "exclude": ["logging"]},
+//	    {"pattern": "/*/List*", "interceptors": ["pagination"]}
+//	  ]
+//	}
+//
+// YAML configurations have the same field names and are decoded with
+// `LoadYAML`.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config describes the levels of a router.
+type Config struct {
+	// Levels are the levels of the router. A same level can be described
+	// several times, in which case its interceptors are added in order.
+	Levels []Level `json:"levels" yaml:"levels"`
+}
+
+// Level describes the interceptors of a level of a router.
+type Level struct {
+	// Package is the protobuf package of the level. The level is the global
+	// level if `Package`, `Service` and `Pattern` are all empty.
+	Package string `json:"package,omitempty" yaml:"package,omitempty"`
+	// Service is the service of the level, within `Package`. Services without
+	// package (e.g. "/Health/Check") have an empty `Package`.
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+	// Method is the method of the level, within `Service`.
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Pattern is the route pattern the level is bound to (see
+	// `AddPattern`). It cannot be used along with `Package`, `Service` and
+	// `Method`.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Interceptors are the interceptors added to the level.
+	Interceptors []Interceptor `json:"interceptors,omitempty" yaml:"interceptors,omitempty"`
+	// Exclude are the names of the interceptors inherited from the levels
+	// above that are excluded from the level (see `Exclude`).
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// Interceptor references the interceptors registered at `Name`, or created
+// with `Params` by the factory registered at `Name` (see
+// `registry.Registry.SetServerFactory`).
+//
+// It can be decoded from a string holding only its name.
+type Interceptor struct {
+	Name   string            `json:"name" yaml:"name"`
+	Params map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// Load decodes a JSON configuration from `r` and validates its structure.
+func Load(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	cfg := &Config{}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadYAML decodes a YAML configuration from `r` and validates its structure.
+func LoadYAML(r io.Reader) (*Config, error) {
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+	cfg := &Config{}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile decodes the configuration of the file at `path` and validates its
+// structure. Files with the ".yaml" or ".yml" extension are decoded as YAML,
+// any other as JSON.
+func LoadFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return LoadYAML(f)
+	}
+	return Load(f)
+}
+
+// validate checks the structure of every level of the configuration.
+func (c *Config) validate() error {
+	for _, lvl := range c.Levels {
+		if err := lvl.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns the route or pattern of the level. The service of a level
+// without package is not prefixed by any package (e.g. "/Health/Check").
+func (l *Level) String() string {
+	service := l.Service
+	if l.Package != "" {
+		service = l.Package + "." + l.Service
+	}
+	switch {
+	case l.Pattern != "":
+		return l.Pattern
+	case l.Package == "" && l.Service == "":
+		return "global"
+	case l.Service == "":
+		return "/" + l.Package
+	case l.Method == "":
+		return "/" + service
+	}
+	return "/" + service + "/" + l.Method
+}
+
+// validate checks that the level describes a single level of a router.
+func (l *Level) validate() error {
+	switch {
+	case l.Pattern != "" && (l.Package != "" || l.Service != "" || l.Method != ""):
+		return fmt.Errorf("Level %s: a pattern cannot be used along with a package, service or method", l)
+	case l.Method != "" && l.Service == "":
+		return fmt.Errorf("Level %s: method %s has no service", l, l.Method)
+	}
+	for _, intcp := range l.Interceptors {
+		if intcp.Name == "" {
+			return fmt.Errorf("Level %s: interceptor without name", l)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes an interceptor from either its name or an object.
+// Unknown fields are rejected, as in the rest of the configuration.
+func (i *Interceptor) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &i.Name); err == nil {
+		return nil
+	}
+	type interceptor Interceptor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*interceptor)(i))
+}
+
+// UnmarshalYAML decodes an interceptor from either its name or a mapping.
+func (i *Interceptor) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&i.Name); err == nil {
+		return nil
+	}
+	type interceptor Interceptor
+	return unmarshal((*interceptor)(i))
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadedLevels are the levels described by both `jsonConfig` and `yamlConfig`.
+var loadedLevels = []Level{
+	{Interceptors: []Interceptor{{Name: "recovery"}}},
+	{
+		Package: "pb",
+		Service: "Service",
+		Interceptors: []Interceptor{
+			{Name: "logging"},
+			{Name: "ratelimit", Params: map[string]string{"rps": "10"}},
+		},
+	},
+	{Package: "pb", Service: "Service", Method: "Health", Exclude: []string{"logging"}},
+	{Pattern: "/*/List*", Interceptors: []Interceptor{{Name: "pagination"}}},
+}
+
+const jsonConfig = `{
+  "levels": [
+    {"interceptors": ["recovery"]},
+    {"package": "pb", "service": "Service", "interceptors": [
+      "logging",
+      {"name": "ratelimit", "params": {"rps": "10"}}
+    ]},
+    {"package": "pb", "service": "Service", "method": "Health", "exclude": ["logging"]},
+    {"pattern": "/*/List*", "interceptors": ["pagination"]}
+  ]
+}`
+
+const yamlConfig = `
+levels:
+  - interceptors: [recovery]
+  - package: pb
+    service: Service
+    interceptors:
+      - logging
+      - name: ratelimit
+        params: {rps: "10"}
+  - package: pb
+    service: Service
+    method: Health
+    exclude: [logging]
+  - pattern: /*/List*
+    interceptors: [pagination]
+`
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		load func() (*Config, error)
+	}{
+		{name: "Load", load: func() (*Config, error) { return Load(strings.NewReader(jsonConfig)) }},
+		{name: "LoadYAML", load: func() (*Config, error) { return LoadYAML(strings.NewReader(yamlConfig)) }},
+	}
+	for _, test := range tests {
+		cfg, err := test.load()
+		if err != nil {
+			t.Errorf("%s() = %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.Levels, loadedLevels) {
+			t.Errorf("%s() = %+v, want %+v", test.name, cfg.Levels, loadedLevels)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"interceptors.json": jsonConfig,
+		"interceptors.yaml": yamlConfig,
+		"interceptors.yml":  yamlConfig,
+	} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadFile(path)
+		if err != nil {
+			t.Errorf("LoadFile(%s) = %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.Levels, loadedLevels) {
+			t.Errorf("LoadFile(%s) = %+v, want %+v", name, cfg.Levels, loadedLevels)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		yaml string
+		err  string
+	}{
+		{
+			name: "unknown field",
+			json: `{"levels": [{"package": "pb", "services": "Service"}]}`,
+			yaml: "levels:\n  - package: pb\n    services: Service\n",
+			err:  "services",
+		},
+		{
+			name: "unknown interceptor field",
+			json: `{"levels": [{"interceptors": [{"name": "ratelimit", "param": {}}]}]}`,
+			yaml: "levels:\n  - interceptors:\n      - name: ratelimit\n        param: {}\n",
+			err:  "param",
+		},
+		{
+			name: "interceptor without name",
+			json: `{"levels": [{"package": "pb", "interceptors": [{"params": {"rps": "10"}}]}]}`,
+			yaml: "levels:\n  - package: pb\n    interceptors:\n      - params: {rps: \"10\"}\n",
+			err:  "Level /pb: interceptor without name",
+		},
+		{
+			name: "method without service",
+			json: `{"levels": [{"package": "pb", "method": "Health"}]}`,
+			yaml: "levels:\n  - package: pb\n    method: Health\n",
+			err:  "method Health has no service",
+		},
+		{
+			name: "pattern along with a package",
+			json: `{"levels": [{"package": "pb", "pattern": "/*/List*"}]}`,
+			yaml: "levels:\n  - package: pb\n    pattern: /*/List*\n",
+			err:  "a pattern cannot be used along with a package",
+		},
+	}
+	for _, test := range tests {
+		if _, err := Load(strings.NewReader(test.json)); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: Load() = %v, want an error containing %q", test.name, err, test.err)
+		}
+		if _, err := LoadYAML(strings.NewReader(test.yaml)); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: LoadYAML() = %v, want an error containing %q", test.name, err, test.err)
+		}
+	}
+}
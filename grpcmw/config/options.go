@@ -0,0 +1,39 @@
+package config
+
+import (
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+)
+
+// Option configures how routers are built from a configuration.
+type Option func(*options)
+
+type options struct {
+	router   []grpcmw.RouterOption
+	registry *registry.Registry
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		registry: registry.Default,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRouterOptions sets the options used to create routers.
+func WithRouterOptions(opts ...grpcmw.RouterOption) Option {
+	return func(o *options) {
+		o.router = append(o.router, opts...)
+	}
+}
+
+// WithRegistry sets the registry in which interceptors and factories are
+// looked up, instead of `registry.Default`.
+func WithRegistry(reg *registry.Registry) Option {
+	return func(o *options) {
+		o.registry = reg
+	}
+}
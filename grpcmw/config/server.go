@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+)
+
+// ServerRouter builds a `grpcmw.ServerRouter` from the configuration. It fails
+// if an interceptor is neither registered in the registry (see `WithRegistry`)
+// nor created by a factory of the registry.
+func (c *Config) ServerRouter(opts ...Option) (grpcmw.ServerRouter, error) {
+	o := newOptions(opts)
+	r := grpcmw.NewServerRouter(o.router...)
+	if err := c.setUpServerRouter(r, o); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReloadServerRouter replaces the levels of `r`, including the ones bound to
+// patterns, with those of the configuration (see `grpcmw.ServerRouter.Reload`).
+// `r` is left untouched if the configuration is invalid. The options of `r` are
+// kept: `WithRouterOptions` is ignored.
+func (c *Config) ReloadServerRouter(r grpcmw.ServerRouter, opts ...Option) error {
+	o := newOptions(opts)
+	return r.Reload(func(staging grpcmw.ServerRouter) error {
+		return c.setUpServerRouter(staging, o)
+	})
+}
+
+// setUpServerRouter sets the levels of the configuration up in `r`.
+func (c *Config) setUpServerRouter(r grpcmw.ServerRouter, o *options) error {
+	patterns := make(map[string]grpcmw.ServerInterceptor)
+	for idx := range c.Levels {
+		l := &c.Levels[idx]
+		if err := l.validate(); err != nil {
+			return err
+		}
+		interceptors := make([]grpcmw.ServerInterceptor, 0, len(l.Interceptors))
+		for _, intcp := range l.Interceptors {
+			resolved, err := o.serverInterceptor(intcp, l)
+			if err != nil {
+				return fmt.Errorf("Level %s: %v", l, err)
+			}
+			interceptors = append(interceptors, resolved)
+		}
+		lvl, err := serverLevel(r, l, patterns)
+		if err != nil {
+			return fmt.Errorf("Level %s: %v", l, err)
+		}
+		lvl.Merge(interceptors...)
+		lvl.Exclude(l.Exclude...)
+	}
+	return nil
+}
+
+// serverInterceptor returns the server interceptors referenced by `intcp` for
+// the level `l`. Unlike `registry.Registry.NewServerInterceptor`, it fails if
+// nothing is registered at the name of `intcp`.
+func (o *options) serverInterceptor(intcp Interceptor, l *Level) (grpcmw.ServerInterceptor, error) {
+	if _, ok := o.registry.LookupServerFactory(intcp.Name); !ok {
+		if _, ok := o.registry.LookupServerInterceptor(intcp.Name); !ok {
+			return nil, fmt.Errorf("Interceptor %s is not registered", intcp.Name)
+		}
+	}
+	return o.registry.NewServerInterceptor(intcp.Name, intcp.Params, registry.RouteInfo{
+		Package: l.Package,
+		Service: l.Service,
+		Method:  l.Method,
+		Pattern: l.Pattern,
+	})
+}
+
+// serverLevel returns the level of `r` described by `l`, registering it if
+// needed. Pattern levels are shared through `patterns`.
+func serverLevel(r grpcmw.ServerRouter, l *Level, patterns map[string]grpcmw.ServerInterceptor) (grpcmw.ServerInterceptor, error) {
+	if l.Pattern != "" {
+		lvl, exists := patterns[l.Pattern]
+		if !exists {
+			lvl = grpcmw.NewServerInterceptor(l.Pattern)
+			if err := r.AddPattern(l.Pattern, lvl); err != nil {
+				return nil, err
+			}
+			patterns[l.Pattern] = lvl
+		}
+		return lvl, nil
+	}
+	if l.Package == "" && l.Service == "" {
+		return r.GetRegister(), nil
+	}
+	var lvl grpcmw.ServerInterceptor
+	lvl, _ = r.RegisterPackage(l.Package)
+	for _, index := range []string{l.Service, l.Method} {
+		if index == "" {
+			break
+		}
+		reg, ok := lvl.(grpcmw.ServerInterceptorRegister)
+		if !ok {
+			return nil, fmt.Errorf("Level %s does not implement grpcmw.ServerInterceptorRegister", lvl.Index())
+		}
+		sub, exists := reg.Get(index)
+		if !exists {
+			sub = grpcmw.NewServerInterceptorRegister(index)
+			if err := reg.Register(sub); err != nil {
+				return nil, fmt.Errorf("Level %s: %v", index, err)
+			}
+		}
+		lvl = sub
+	}
+	return lvl, nil
+}
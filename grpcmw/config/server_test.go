@@ -0,0 +1,175 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// recordingInterceptor returns an interceptor whose unary chain records `name`
+// in `calls`.
+func recordingInterceptor(calls *[]string, name string) grpcmw.ServerInterceptor {
+	return grpcmw.NewServerInterceptor(name).AddGRPCUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	})
+}
+
+// resolveUnary calls the unary resolver of `r` for `route` and returns the
+// names recorded in `calls` by the interceptors it called.
+func resolveUnary(t *testing.T, r grpcmw.ServerRouter, calls *[]string, route string) []string {
+	*calls = nil
+	_, err := r.UnaryResolver()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: route}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("%s: %v", route, err)
+	}
+	return *calls
+}
+
+func TestServerRouterServiceWithoutPackage(t *testing.T) {
+	var calls []string
+	reg := registry.New()
+	reg.SetServerInterceptor("recovery", recordingInterceptor(&calls, "recovery"))
+	reg.SetServerInterceptor("health", recordingInterceptor(&calls, "health"))
+	cfg, err := Load(strings.NewReader(`{"levels": [
+		{"interceptors": ["recovery"]},
+		{"service": "Health", "method": "Check", "interceptors": ["health"]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if got := cfg.Levels[1].String(); got != "/Health/Check" {
+		t.Errorf("String() = %q, want /Health/Check", got)
+	}
+	r, err := cfg.ServerRouter(WithRegistry(reg))
+	if err != nil {
+		t.Fatalf("ServerRouter() = %v", err)
+	}
+	if got := resolveUnary(t, r, &calls, "/Health/Check"); !reflect.DeepEqual(got, []string{"recovery", "health"}) {
+		t.Errorf("/Health/Check called %v, want [recovery health]", got)
+	}
+	if got := resolveUnary(t, r, &calls, "/pkg.Health/Check"); !reflect.DeepEqual(got, []string{"recovery"}) {
+		t.Errorf("/pkg.Health/Check called %v, want [recovery]", got)
+	}
+}
+
+func TestServerRouter(t *testing.T) {
+	var calls []string
+	var params map[string]string
+	var route registry.RouteInfo
+	reg := registry.New()
+	for _, name := range []string{"recovery", "logging", "pagination"} {
+		reg.SetServerInterceptor(name, recordingInterceptor(&calls, name))
+	}
+	cfg, err := Load(strings.NewReader(jsonConfig))
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if _, err := cfg.ServerRouter(WithRegistry(reg)); err == nil || !strings.Contains(err.Error(), "ratelimit") {
+		t.Fatalf("ServerRouter() = %v, want an error for the unregistered interceptor ratelimit", err)
+	}
+	reg.SetServerFactory("ratelimit", func(p map[string]string, r registry.RouteInfo) (grpcmw.ServerInterceptor, error) {
+		params, route = p, r
+		return recordingInterceptor(&calls, "ratelimit"), nil
+	})
+
+	r, err := cfg.ServerRouter(WithRegistry(reg))
+	if err != nil {
+		t.Fatalf("ServerRouter() = %v", err)
+	}
+	if want := map[string]string{"rps": "10"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("the factory has been called with %v, want %v", params, want)
+	}
+	if want := (registry.RouteInfo{Package: "pb", Service: "Service"}); route != want {
+		t.Errorf("the factory has been called for %v, want %v", route, want)
+	}
+	for route, want := range map[string][]string{
+		"/pb.Service/Method":    {"recovery", "logging", "ratelimit"},
+		"/pb.Service/Health":    {"recovery", "ratelimit"},
+		"/pb.Service/ListItems": {"recovery", "logging", "ratelimit", "pagination"},
+		"/other.Service/Method": {"recovery"},
+	} {
+		if got := resolveUnary(t, r, &calls, route); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s called %v, want %v", route, got, want)
+		}
+	}
+}
+
+func TestReloadServerRouter(t *testing.T) {
+	var calls []string
+	reg := registry.New()
+	for _, name := range []string{"recovery", "logging", "auth"} {
+		reg.SetServerInterceptor(name, recordingInterceptor(&calls, name))
+	}
+	load := func(text string) *Config {
+		cfg, err := Load(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("Load() = %v", err)
+		}
+		return cfg
+	}
+	first := load(`{"levels": [
+		{"interceptors": ["recovery"]},
+		{"package": "pb", "service": "Service", "interceptors": ["logging"]}
+	]}`)
+	second := load(`{"levels": [
+		{"interceptors": ["recovery"]},
+		{"package": "pb", "interceptors": ["auth"]},
+		{"pattern": "/*/List*", "interceptors": ["logging"]}
+	]}`)
+	invalid := load(`{"levels": [{"interceptors": ["unregistered"]}]}`)
+
+	r, err := first.ServerRouter(WithRegistry(reg))
+	if err != nil {
+		t.Fatalf("ServerRouter() = %v", err)
+	}
+	steps := []struct {
+		name   string
+		cfg    *Config
+		fails  bool
+		routes map[string][]string
+	}{
+		{
+			name: "second",
+			cfg:  second,
+			routes: map[string][]string{
+				"/pb.Service/Method":    {"recovery", "auth"},
+				"/pb.Service/ListItems": {"recovery", "auth", "logging"},
+			},
+		},
+		{
+			name:  "invalid",
+			cfg:   invalid,
+			fails: true,
+			routes: map[string][]string{
+				"/pb.Service/Method":    {"recovery", "auth"},
+				"/pb.Service/ListItems": {"recovery", "auth", "logging"},
+			},
+		},
+		{
+			name: "first",
+			cfg:  first,
+			routes: map[string][]string{
+				"/pb.Service/Method":    {"recovery", "logging"},
+				"/pb.Service/ListItems": {"recovery", "logging"},
+			},
+		},
+	}
+	for _, step := range steps {
+		if err := step.cfg.ReloadServerRouter(r, WithRegistry(reg)); (err != nil) != step.fails {
+			t.Errorf("%s: ReloadServerRouter() = %v, want failure %v", step.name, err, step.fails)
+		}
+		for route, want := range step.routes {
+			if got := resolveUnary(t, r, &calls, route); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s called %v, want %v", step.name, route, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package grpcmw
+
+import "errors"
+
+var (
+	// ErrInterceptorNotFound is returned when no interceptor has been added to
+	// a chain under the requested name.
+	ErrInterceptorNotFound = errors.New("Interceptor not found")
+	// ErrFrozen is returned when trying to modify interceptors that have been
+	// frozen.
+	ErrFrozen = errors.New("Interceptors are frozen")
+)
@@ -0,0 +1,32 @@
+package grpcmw
+
+import "google.golang.org/grpc/grpclog"
+
+// FreezeMode defines how modifications of frozen interceptors are handled.
+type FreezeMode int
+
+const (
+	// FreezeError makes modifications of frozen interceptors fail with
+	// `ErrFrozen`. Modifications made through methods that do not return any
+	// error are ignored and reported with `grpclog`.
+	FreezeError FreezeMode = iota
+	// FreezePanic makes modifications of frozen interceptors panic with
+	// `ErrFrozen`.
+	FreezePanic
+)
+
+// frozenError returns `ErrFrozen`, or panics with it if `mode` is
+// `FreezePanic`.
+func frozenError(mode FreezeMode) error {
+	if mode == FreezePanic {
+		panic(ErrFrozen)
+	}
+	return ErrFrozen
+}
+
+// ignoreFrozen handles a modification of frozen interceptors made through a
+// method that does not return any error: it panics with `ErrFrozen` if `mode`
+// is `FreezePanic`, and reports that the modification is ignored otherwise.
+func ignoreFrozen(mode FreezeMode) {
+	grpclog.Warningf("Modification ignored: %v", frozenError(mode))
+}
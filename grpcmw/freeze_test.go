@@ -0,0 +1,138 @@
+package grpcmw
+
+import "testing"
+
+// frozenServerRouter returns a router defining "/pkg.Service/Method", whose
+// global level has an interceptor named "auth" and whose package merges
+// `shared`, along with its service level.
+func frozenServerRouter(calls *[]string, shared ServerInterceptor) (ServerRouter, ServerInterceptorRegister) {
+	r := NewServerRouter()
+	r.GetRegister().AddNamedGRPCUnaryInterceptor("auth", recordServerUnary(calls, "auth"))
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.Merge(shared)
+	service := NewServerInterceptorRegister("Service")
+	service.Register(NewServerInterceptor("Method"))
+	pkg.Register(service)
+	return r, service
+}
+
+func TestServerRouterFreeze(t *testing.T) {
+	var calls []string
+	shared := NewServerInterceptor("shared")
+	shared.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "shared"))
+	r, service := frozenServerRouter(&calls, shared)
+	resolver := r.UnaryResolver()
+	r.Freeze(FreezeError)
+	if !r.Frozen() || !r.GetRegister().Frozen() {
+		t.Fatal("the router and its register are not frozen")
+	}
+
+	want := []string{"auth", "shared"}
+	steps := []struct {
+		name   string
+		modify func() error
+	}{
+		{
+			name: "Remove",
+			modify: func() error {
+				return r.GetRegister().UnaryServerInterceptor().Remove("auth")
+			},
+		},
+		{
+			name: "ReplaceGRPCUnaryInterceptor",
+			modify: func() error {
+				return r.GetRegister().ReplaceGRPCUnaryInterceptor("auth", recordServerUnary(&calls, "replaced"))
+			},
+		},
+		{
+			name:   "Register",
+			modify: func() error { return service.Register(NewServerInterceptor("Other")) },
+		},
+		{
+			name:   "SetRegister",
+			modify: func() error { return r.SetRegister(NewServerInterceptorRegister("global")) },
+		},
+		{
+			name: "AddPattern",
+			modify: func() error {
+				return r.AddPattern("/*/*", NewServerInterceptor("pattern"))
+			},
+		},
+	}
+	for _, step := range steps {
+		if err := step.modify(); err != ErrFrozen {
+			t.Errorf("%s = %v, want ErrFrozen", step.name, err)
+		}
+	}
+
+	// Modifications made through methods that do not return any error are
+	// ignored.
+	r.GetRegister().AddGRPCUnaryInterceptor(recordServerUnary(&calls, "ignored"))
+	service.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "ignored"))
+	service.Exclude("auth")
+	if got := callServerUnary(t, resolver, &calls, "/pkg.Service/Method"); !equalStrings(got, want) {
+		t.Errorf("frozen router called %q, want %q", got, want)
+	}
+}
+
+func TestServerRouterFreezePanic(t *testing.T) {
+	var calls []string
+	r, service := frozenServerRouter(&calls, NewServerInterceptor("shared"))
+	r.Freeze(FreezePanic)
+	steps := []struct {
+		name   string
+		modify func()
+	}{
+		{
+			name: "AddGRPCUnaryInterceptor",
+			modify: func() {
+				r.GetRegister().AddGRPCUnaryInterceptor(recordServerUnary(&calls, "ignored"))
+			},
+		},
+		{
+			name:   "RemoveInterceptor",
+			modify: func() { r.GetRegister().RemoveInterceptor("auth") },
+		},
+		{
+			name:   "Register",
+			modify: func() { service.Register(NewServerInterceptor("Other")) },
+		},
+		{
+			name:   "SetRegister",
+			modify: func() { r.SetRegister(NewServerInterceptorRegister("global")) },
+		},
+	}
+	for _, step := range steps {
+		func() {
+			defer func() {
+				if err := recover(); err != ErrFrozen {
+					t.Errorf("%s panicked with %v, want ErrFrozen", step.name, err)
+				}
+			}()
+			step.modify()
+		}()
+	}
+}
+
+func TestServerRouterFrozenRoutesStable(t *testing.T) {
+	var calls []string
+	shared := NewServerInterceptor("shared")
+	shared.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "shared"))
+	r, _ := frozenServerRouter(&calls, shared)
+	resolver := r.UnaryResolver()
+	r.Freeze(FreezeError)
+
+	// Merged chains are not frozen, as they may be shared with other routers,
+	// but the routes the router defines keep on calling the interceptors they
+	// had when it was frozen.
+	shared.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "added"))
+	if shared.Frozen() {
+		t.Fatal("the merged level has been frozen along with the router")
+	}
+	want := []string{"auth", "shared"}
+	for i := 0; i < 2; i++ {
+		if got := callServerUnary(t, resolver, &calls, "/pkg.Service/Method"); !equalStrings(got, want) {
+			t.Fatalf("call %d: frozen route called %q, want %q", i, got, want)
+		}
+	}
+}
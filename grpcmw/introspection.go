@@ -0,0 +1,194 @@
+package grpcmw
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// InterceptorInfo describes an element of a chain of interceptors.
+type InterceptorInfo struct {
+	// Name is the name the element has been added under, if any.
+	Name string `json:"name,omitempty"`
+	// Priority is the priority of the element in its chain.
+	Priority Priority `json:"priority"`
+	// Reference tells whether the element is a chain of interceptors that has
+	// been added by reference (see `AddInterceptor`).
+	Reference bool `json:"reference,omitempty"`
+	// Chain describes the elements of the referenced chain, if any.
+	Chain []InterceptorInfo `json:"chain,omitempty"`
+}
+
+// LevelInfo describes a level of interceptors and its sublevels.
+type LevelInfo struct {
+	// Path is the list of indexes leading to the level from the level the
+	// description has been made from. It is empty for the latter.
+	Path []string `json:"path"`
+	// Index is the index of the level.
+	Index string `json:"index"`
+	// Register tells whether the level is a register, i.e. whether it can have
+	// sublevels.
+	Register bool `json:"register"`
+	// UnaryCount is the number of unary interceptors called by the level.
+	UnaryCount int `json:"unaryCount"`
+	// StreamCount is the number of stream interceptors called by the level.
+	StreamCount int `json:"streamCount"`
+	// Unary describes the chain of unary interceptors of the level.
+	Unary []InterceptorInfo `json:"unary,omitempty"`
+	// Stream describes the chain of stream interceptors of the level.
+	Stream []InterceptorInfo `json:"stream,omitempty"`
+	// Excluded lists the names excluded from the parent levels (see
+	// `Exclude`).
+	Excluded []string `json:"excluded,omitempty"`
+	// Sublevels describes the sublevels of the level, sorted by index.
+	Sublevels []*LevelInfo `json:"sublevels,omitempty"`
+}
+
+// RouteInfo describes the interceptors called for a route.
+type RouteInfo struct {
+	// Route is the route the description has been made for.
+	Route string `json:"route"`
+	// Known is false if the route is unknown (see `UnknownRoutePolicy`).
+	Known bool `json:"known"`
+	// Policy is the policy of the router for unknown routes. If the route is
+	// unknown and the policy is not `UnknownRoutePrefix`, `Levels` is empty and
+	// `Unary` and `Stream` list the interceptors called by the policy: none
+	// with `UnknownRoutePassThrough` and `UnknownRouteReject`, which rejects
+	// the requests, and those of the fallback middleware, reported under the
+	// "fallback" level, with `UnknownRouteFallback`.
+	Policy UnknownRoutePolicy `json:"policy"`
+	// Levels is the list of indexes of the levels on the path of the route,
+	// starting from the global level.
+	Levels []string `json:"levels"`
+	// Unary lists the unary interceptors called for the route, in order.
+	Unary []ResolvedInterceptor `json:"unary"`
+	// Stream lists the stream interceptors called for the route, in order.
+	Stream []ResolvedInterceptor `json:"stream"`
+}
+
+// ResolvedInterceptor describes an interceptor called for a route.
+type ResolvedInterceptor struct {
+	// Level is the index of the level the interceptor comes from.
+	Level string `json:"level"`
+	// Chains is the list of names of the chains added by reference (see
+	// `AddInterceptor`) that lead to the interceptor within its level.
+	Chains []string `json:"chains,omitempty"`
+	// Name is the name the interceptor has been added under, if any.
+	Name string `json:"name,omitempty"`
+	// Priority is the priority of the interceptor in its chain.
+	Priority Priority `json:"priority"`
+}
+
+// Has returns whether the interceptor has been added under `name` or comes
+// from a chain that has been added under `name`.
+func (i ResolvedInterceptor) Has(name string) bool {
+	if i.Name == name {
+		return true
+	}
+	for _, chain := range i.Chains {
+		if chain == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUnary returns whether a unary interceptor named `name`, or coming from a
+// chain named `name`, is called for the route.
+func (info *RouteInfo) HasUnary(name string) bool {
+	return hasResolvedInterceptor(info.Unary, name)
+}
+
+// HasStream returns whether a stream interceptor named `name`, or coming from
+// a chain named `name`, is called for the route.
+func (info *RouteInfo) HasStream(name string) bool {
+	return hasResolvedInterceptor(info.Stream, name)
+}
+
+func hasResolvedInterceptor(arr []ResolvedInterceptor, name string) bool {
+	for _, i := range arr {
+		if i.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes a human-readable tree view of the level and its sublevels
+// to `w`.
+func (info *LevelInfo) WriteText(w io.Writer) error {
+	return info.writeText(w, 0)
+}
+
+// WriteJSON writes the JSON form of the level and its sublevels to `w`.
+func (info *LevelInfo) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(info)
+}
+
+func (info *LevelInfo) writeText(w io.Writer, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	kind := "level"
+	if info.Register {
+		kind = "register"
+	}
+	if _, err := fmt.Fprintf(w, "%s%s (%s, unary: %d, stream: %d)\n", indent, info.Index, kind, info.UnaryCount, info.StreamCount); err != nil {
+		return err
+	}
+	if err := writeInterceptorInfos(w, indent+"  unary:", indent+"    ", info.Unary); err != nil {
+		return err
+	}
+	if err := writeInterceptorInfos(w, indent+"  stream:", indent+"    ", info.Stream); err != nil {
+		return err
+	}
+	if len(info.Excluded) > 0 {
+		if _, err := fmt.Fprintf(w, "%s  excluded: %s\n", indent, strings.Join(info.Excluded, ", ")); err != nil {
+			return err
+		}
+	}
+	for _, sub := range info.Sublevels {
+		if err := sub.writeText(w, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeInterceptorInfos(w io.Writer, header, indent string, infos []InterceptorInfo) error {
+	if len(infos) == 0 {
+		return nil
+	}
+	if header != "" {
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return err
+		}
+	}
+	for _, info := range infos {
+		name := info.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		if info.Reference {
+			name += " (chain)"
+		}
+		if info.Priority != PhaseDefault {
+			name += fmt.Sprintf(" [priority: %d]", info.Priority)
+		}
+		if _, err := fmt.Fprintf(w, "%s- %s\n", indent, name); err != nil {
+			return err
+		}
+		if err := writeInterceptorInfos(w, "", indent+"  ", info.Chain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendPath returns a copy of `path` with `indexes` appended to it.
+func appendPath(path []string, indexes ...string) []string {
+	ret := make([]string, 0, len(path)+len(indexes))
+	ret = append(ret, path...)
+	return append(ret, indexes...)
+}
@@ -0,0 +1,74 @@
+package grpcmw
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// levelBase holds what the levels of both sides share besides their chains of
+// interceptors: their index and the names they exclude.
+type levelBase struct {
+	index    string
+	excluded *atomic.Value // []string
+	lock     *sync.Mutex
+	frozen   bool
+	mode     FreezeMode
+}
+
+func newLevelBase(index string) *levelBase {
+	l := &levelBase{
+		index:    index,
+		excluded: &atomic.Value{},
+		lock:     &sync.Mutex{},
+	}
+	l.excluded.Store([]string(nil))
+	return l
+}
+
+// Index returns the index of the level.
+func (l *levelBase) Index() string {
+	return l.index
+}
+
+// Excluded returns the names excluded by the level.
+func (l *levelBase) Excluded() []string {
+	return l.excluded.Load().([]string)
+}
+
+// exclude adds `names` to the names excluded by the level.
+func (l *levelBase) exclude(names []string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.frozen {
+		ignoreFrozen(l.mode)
+		return
+	}
+	current := l.Excluded()
+	excluded := make([]string, len(current), len(current)+len(names))
+	copy(excluded, current)
+	l.excluded.Store(append(excluded, names...))
+	invalidateRoutes()
+}
+
+// freeze makes the names excluded by the level immutable.
+func (l *levelBase) freeze(mode FreezeMode) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.frozen, l.mode = true, mode
+}
+
+// removeError returns the error of removing an interceptor from both the
+// unary and the stream chains of a level, given the error of each removal:
+// `ErrInterceptorNotFound` is only returned if neither of them had such an
+// interceptor.
+func removeError(unaryErr, streamErr error) error {
+	switch {
+	case unaryErr != nil && unaryErr != ErrInterceptorNotFound:
+		return unaryErr
+	case streamErr != nil && streamErr != ErrInterceptorNotFound:
+		return streamErr
+	case unaryErr != nil && streamErr != nil:
+		return ErrInterceptorNotFound
+	}
+	return nil
+}
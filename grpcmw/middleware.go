@@ -0,0 +1,24 @@
+package grpcmw
+
+import "google.golang.org/grpc"
+
+// Middleware groups the interceptors that implement a same concern (e.g.
+// logging or metadata propagation) for both server and client sides, for unary
+// and stream requests. Any of them can be nil, in which case it is simply not
+// added. The same middleware can be added to server and client levels alike
+// with their method `AddMiddleware`.
+type Middleware struct {
+	// Name is the name under which the interceptors are added (see
+	// `AddNamed`). If it is empty, they are added anonymously.
+	Name string
+	// Priority is the priority of the interceptors (see `Priority`).
+	Priority Priority
+	// UnaryServer is added to chains of `UnaryServerInterceptor`.
+	UnaryServer grpc.UnaryServerInterceptor
+	// StreamServer is added to chains of `StreamServerInterceptor`.
+	StreamServer grpc.StreamServerInterceptor
+	// UnaryClient is added to chains of `UnaryClientInterceptor`.
+	UnaryClient grpc.UnaryClientInterceptor
+	// StreamClient is added to chains of `StreamClientInterceptor`.
+	StreamClient grpc.StreamClientInterceptor
+}
@@ -0,0 +1,112 @@
+package grpcmw
+
+import "fmt"
+
+// RouterOption configures a `ServerRouter` or a `ClientRouter`.
+type RouterOption func(*routerOptions)
+
+// routerOptions holds the configuration of a router.
+type routerOptions struct {
+	nestedPackages bool
+	unknownRoutes  UnknownRoutePolicy
+	fallback       Middleware
+	onUnknownRoute func(route string, err error)
+}
+
+// newRouterOptions applies `opts` to the default configuration of a router.
+func newRouterOptions(opts []RouterOption) routerOptions {
+	options := routerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithNestedPackages makes each segment of the protobuf package of a route its
+// own level. For instance, the route "/company.billing.v1.Invoices/Get" then
+// goes through the levels "company", "company.billing" and
+// "company.billing.v1" before the service level "Invoices", so that
+// interceptors registered at the level "company" apply to all its subpackages.
+// Package levels are indexed by the package they stand for, so that they never
+// share their index with the levels of services.
+func WithNestedPackages() RouterOption {
+	return func(options *routerOptions) {
+		options.nestedPackages = true
+	}
+}
+
+// UnknownRoutePolicy defines how a router handles the requests to unknown
+// routes, i.e. routes that cannot be parsed (for instance because they have a
+// trailing slash) or whose service level has not been registered, unless they
+// match a pattern (see `ServerRouter.AddPattern`): routes matching a pattern are
+// known, and are called with the levels found on their path as well.
+type UnknownRoutePolicy int
+
+const (
+	// UnknownRoutePrefix calls the interceptors of the levels that have been
+	// found on the path of unknown routes. Their chains of interceptors are
+	// compiled for each request, as only known routes are cached. Routes that
+	// cannot be parsed are rejected with `codes.Internal`. This is the default
+	// policy.
+	UnknownRoutePrefix UnknownRoutePolicy = iota
+	// UnknownRoutePassThrough calls the handler (or the invoker) of unknown
+	// routes without any interceptor.
+	UnknownRoutePassThrough
+	// UnknownRouteFallback calls the interceptors of the fallback middleware
+	// for unknown routes (see `WithFallback`).
+	UnknownRouteFallback
+	// UnknownRouteReject rejects the requests to unknown routes with
+	// `codes.Unimplemented`.
+	UnknownRouteReject
+)
+
+// fallbackLevel is the level the interceptors of the fallback middleware are
+// reported under by `Resolve`.
+const fallbackLevel = "fallback"
+
+var unknownRoutePolicyNames = map[UnknownRoutePolicy]string{
+	UnknownRoutePrefix:      "prefix",
+	UnknownRoutePassThrough: "passthrough",
+	UnknownRouteFallback:    "fallback",
+	UnknownRouteReject:      "reject",
+}
+
+// String returns the name of the policy (e.g. "reject").
+func (p UnknownRoutePolicy) String() string {
+	if name, ok := unknownRoutePolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("UnknownRoutePolicy(%d)", int(p))
+}
+
+// MarshalText encodes the policy as its name, so that it is readable in JSON.
+func (p UnknownRoutePolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// WithUnknownRoutePolicy sets how the router handles the requests to unknown
+// routes.
+func WithUnknownRoutePolicy(policy UnknownRoutePolicy) RouterOption {
+	return func(options *routerOptions) {
+		options.unknownRoutes = policy
+	}
+}
+
+// WithFallback makes the router call the interceptors of `m` instead of those
+// of the tree for the requests to unknown routes. It implies the
+// `UnknownRouteFallback` policy.
+func WithFallback(m Middleware) RouterOption {
+	return func(options *routerOptions) {
+		options.unknownRoutes = UnknownRouteFallback
+		options.fallback = m
+	}
+}
+
+// WithUnknownRouteCallback makes the router call `fn` for each request to an
+// unknown route, whatever the policy. `err` is the reason why the route could
+// not be parsed, if any.
+func WithUnknownRouteCallback(fn func(route string, err error)) RouterOption {
+	return func(options *routerOptions) {
+		options.onUnknownRoute = fn
+	}
+}
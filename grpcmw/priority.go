@@ -0,0 +1,21 @@
+package grpcmw
+
+// Priority defines the position of an interceptor in its chain. Interceptors
+// are called by ascending priority, so interceptors with a lower priority wrap
+// the ones with a higher priority. Interceptors with the same priority are
+// called in the order they have been added.
+type Priority int
+
+// Predefined priorities, from the outermost to the innermost phase.
+const (
+	// PhaseObservability is meant for interceptors such as tracing, metrics or
+	// logging that must see every request, including rejected ones.
+	PhaseObservability Priority = -300
+	// PhaseAuth is meant for authentication and authorization interceptors.
+	PhaseAuth Priority = -200
+	// PhaseValidation is meant for interceptors validating the requests of
+	// authorized callers.
+	PhaseValidation Priority = -100
+	// PhaseDefault is the priority of interceptors added without any.
+	PhaseDefault Priority = 0
+)
@@ -1,44 +1,311 @@
 package registry
 
 import (
-	"sync"
+	"fmt"
 
 	"github.com/MarquisIO/go-grpcmw/grpcmw"
 )
 
-var (
-	clientLock     sync.Mutex
-	clientRegistry = make(map[string]grpcmw.ClientInterceptor)
-)
-
 // GetClientInterceptor returns the `grpcmw.ClientInterceptor` registered at
 // `index`. If nothing is at this `index`, it registers a new one using
 // `grpcmw.NewClientInterceptor` and returns it.
 // This is thread-safe.
-func GetClientInterceptor(index string) grpcmw.ClientInterceptor {
-	clientLock.Lock()
-	defer clientLock.Unlock()
-	intcp, ok := clientRegistry[index]
+func (r *Registry) GetClientInterceptor(index string) grpcmw.ClientInterceptor {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	intcp, ok := r.clientRegistry[index]
 	if !ok {
 		intcp = grpcmw.NewClientInterceptor(index)
-		clientRegistry[index] = intcp
+		r.setClientInterceptor(index, intcp)
 	}
 	return intcp
 }
 
+// LookupClientInterceptor returns the `grpcmw.ClientInterceptor` registered at
+// `index`, if any. Unlike `GetClientInterceptor`, it never registers a new one.
+// This is thread-safe.
+func (r *Registry) LookupClientInterceptor(index string) (interceptor grpcmw.ClientInterceptor, exists bool) {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	interceptor, exists = r.clientRegistry[index]
+	return
+}
+
 // SetClientInterceptor registers `interceptor` at `index`. It replaces any
-// interceptor that has been previously registered at this `index`.
+// interceptor that has been previously registered at this `index`, including
+// in the levels using it through `BindClientInterceptor`.
 // This is thread-safe.
-func SetClientInterceptor(index string, interceptor grpcmw.ClientInterceptor) {
-	clientLock.Lock()
-	defer clientLock.Unlock()
-	clientRegistry[index] = interceptor
+func (r *Registry) SetClientInterceptor(index string, interceptor grpcmw.ClientInterceptor) {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	r.setClientInterceptor(index, interceptor)
+}
+
+// setClientInterceptor registers `interceptor` at `index` and binds the binding
+// of `index` to it, if any. It must be called with the lock held.
+func (r *Registry) setClientInterceptor(index string, interceptor grpcmw.ClientInterceptor) {
+	r.clientRegistry[index] = interceptor
+	if binding, exists := r.clientBindings[index]; exists {
+		bindClientInterceptor(binding, index, interceptor)
+	}
+}
+
+// DeleteClientInterceptor deletes any interceptor registered at `index`. The
+// levels using it through `BindClientInterceptor` stop calling it.
+// This is thread-safe.
+func (r *Registry) DeleteClientInterceptor(index string) {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	delete(r.clientRegistry, index)
+	if binding, exists := r.clientBindings[index]; exists {
+		unbindClientInterceptor(binding, index)
+	}
+}
+
+// BindClientInterceptor returns a `grpcmw.ClientInterceptor` indexed by `index`
+// whose chains reference those of the interceptor registered at `index`,
+// whichever it is: replacing it with `SetClientInterceptor` or deleting it with
+// `DeleteClientInterceptor` takes effect everywhere the returned interceptor is
+// merged, without rebuilding the routers. The same interceptor is returned for
+// each call with the same `index`. Frozen routers keep the interceptors it
+// referenced when they have been frozen.
+// This is thread-safe.
+func (r *Registry) BindClientInterceptor(index string) grpcmw.ClientInterceptor {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	binding, exists := r.clientBindings[index]
+	if !exists {
+		binding = grpcmw.NewClientInterceptor(index)
+		r.clientBindings[index] = binding
+		if interceptor, registered := r.clientRegistry[index]; registered {
+			bindClientInterceptor(binding, index, interceptor)
+		}
+	}
+	return binding
+}
+
+// bindClientInterceptor makes the chains of `binding` reference those of
+// `interceptor` under `index`, replacing any previous reference. Frozen chains
+// are left untouched.
+func bindClientInterceptor(binding grpcmw.ClientInterceptor, index string, interceptor grpcmw.ClientInterceptor) {
+	if unaries := binding.UnaryClientInterceptor(); !unaries.Frozen() {
+		unaries.AddNamedInterceptor(index, interceptor.UnaryClientInterceptor())
+	}
+	if streams := binding.StreamClientInterceptor(); !streams.Frozen() {
+		streams.AddNamedInterceptor(index, interceptor.StreamClientInterceptor())
+	}
+}
+
+// unbindClientInterceptor removes the reference held by the chains of `binding`
+// under `index`. Frozen chains are left untouched.
+func unbindClientInterceptor(binding grpcmw.ClientInterceptor, index string) {
+	if unaries := binding.UnaryClientInterceptor(); !unaries.Frozen() {
+		unaries.Remove(index)
+	}
+	if streams := binding.StreamClientInterceptor(); !streams.Frozen() {
+		streams.Remove(index)
+	}
+}
+
+// clientFactoryBinding is an interceptor created by the factory of an index for
+// a route with `params`, whose chains reference the ones created by the
+// factory so that they can be created again when the factory is replaced.
+type clientFactoryBinding struct {
+	params  map[string]string
+	binding grpcmw.ClientInterceptor
+}
+
+// SetClientFactory registers `factory` at `index`. It replaces any factory that
+// has been previously registered at this `index`. Interceptors created with
+// `NewClientInterceptor` at this `index` are created by `factory` instead of
+// being the ones registered at this `index`.
+//
+// The interceptors created by the previous factories for each route (see
+// `NewClientInterceptor`) are created again by `factory`, with the same
+// parameters, and replaced everywhere they have been merged, without
+// rebuilding the routers. It fails without registering
+// `factory` if it fails for any of them. Frozen routers keep the interceptors
+// they have been frozen with. Factories must not call `NewClientInterceptor`,
+// `SetClientFactory` or `DeleteClientFactory`.
+// This is thread-safe.
+func (r *Registry) SetClientFactory(index string, factory ClientFactory) error {
+	r.clientFactoryLock.Lock()
+	defer r.clientFactoryLock.Unlock()
+	bindings := r.clientFactoryBindings[index]
+	created := make(map[RouteInfo]grpcmw.ClientInterceptor, len(bindings))
+	for route, b := range bindings {
+		intcp, err := factory(b.params, route)
+		if err != nil {
+			return fmt.Errorf("Index %s for %s: %v", index, route, err)
+		}
+		created[route] = intcp
+	}
+	r.clientLock.Lock()
+	r.clientFactories[index] = factory
+	r.clientLock.Unlock()
+	for route, b := range bindings {
+		bindClientInterceptor(b.binding, index, created[route])
+	}
+	return nil
+}
+
+// LookupClientFactory returns the factory registered at `index`, if any.
+// This is thread-safe.
+func (r *Registry) LookupClientFactory(index string) (factory ClientFactory, exists bool) {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	factory, exists = r.clientFactories[index]
+	return
+}
+
+// DeleteClientFactory deletes any factory registered at `index`. The
+// interceptors it has created are removed from everywhere they have been
+// merged, until another factory is registered at `index`.
+// This is thread-safe.
+func (r *Registry) DeleteClientFactory(index string) {
+	r.clientFactoryLock.Lock()
+	defer r.clientFactoryLock.Unlock()
+	r.clientLock.Lock()
+	delete(r.clientFactories, index)
+	r.clientLock.Unlock()
+	for _, b := range r.clientFactoryBindings[index] {
+		unbindClientInterceptor(b.binding, index)
+	}
 }
 
-// DeleteClientInterceptor deletes any interceptor registered at `index`.
+// NewClientInterceptor returns the interceptors of `index` for the level
+// described by `route`. If a factory is registered at `index`, they are created
+// by the factory with `params` and bound under `index`, so that they are
+// created again when the factory is replaced (see `SetClientFactory`). Only the
+// interceptors created last for each route are tracked this way, so that
+// rebuilding or reloading routers does not accumulate them: they are shared by
+// the levels of the same route as long as `params` do not change. Otherwise,
+// `params` must be empty and they are the ones registered at `index`, bound
+// with `BindClientInterceptor` so that they can be replaced later on. Once they
+// have been created, the route is recorded as referencing `index` (see
+// `ListClient`).
 // This is thread-safe.
+func (r *Registry) NewClientInterceptor(index string, params map[string]string, route RouteInfo) (grpcmw.ClientInterceptor, error) {
+	r.clientFactoryLock.Lock()
+	defer r.clientFactoryLock.Unlock()
+	factory, exists := r.LookupClientFactory(index)
+	if !exists {
+		if len(params) > 0 {
+			return nil, errNoFactory(index)
+		}
+		r.referenceClientInterceptor(index, route)
+		return r.BindClientInterceptor(index), nil
+	}
+	bindings, exists := r.clientFactoryBindings[index]
+	if !exists {
+		bindings = make(map[RouteInfo]*clientFactoryBinding)
+		r.clientFactoryBindings[index] = bindings
+	}
+	if b, exists := bindings[route]; exists && equalParams(b.params, params) {
+		return b.binding, nil
+	}
+	created, err := factory(params, route)
+	if err != nil {
+		return nil, fmt.Errorf("Index %s for %s: %v", index, route, err)
+	}
+	r.referenceClientInterceptor(index, route)
+	binding := grpcmw.NewClientInterceptor(index)
+	bindClientInterceptor(binding, index, created)
+	bindings[route] = &clientFactoryBinding{
+		params:  params,
+		binding: binding,
+	}
+	return binding, nil
+}
+
+// referenceClientInterceptor records that `route` references `index`.
+func (r *Registry) referenceClientInterceptor(index string, route RouteInfo) {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	routes, exists := r.clientRoutes[index]
+	if !exists {
+		routes = make(map[string]struct{})
+		r.clientRoutes[index] = routes
+	}
+	routes[route.String()] = struct{}{}
+}
+
+// ListClient returns the description of the indexes at which client
+// interceptors or factories are registered, sorted by index.
+// This is thread-safe.
+func (r *Registry) ListClient() []IndexInfo {
+	r.clientLock.Lock()
+	infos := make(map[string]*IndexInfo, len(r.clientRegistry)+len(r.clientFactories))
+	for index, intcp := range r.clientRegistry {
+		infos[index] = &IndexInfo{
+			Index:  index,
+			Unary:  len(intcp.UnaryClientInterceptor().Describe()) > 0,
+			Stream: len(intcp.StreamClientInterceptor().Describe()) > 0,
+		}
+	}
+	for index := range r.clientFactories {
+		if _, exists := infos[index]; !exists {
+			infos[index] = &IndexInfo{Index: index}
+		}
+		infos[index].Factory = true
+	}
+	for index, info := range infos {
+		info.Routes = sortedRoutes(r.clientRoutes[index])
+	}
+	r.clientLock.Unlock()
+	return r.sortedInfos(infos)
+}
+
+// MustNewClientInterceptor is like `NewClientInterceptor` but panics if the
+// interceptors cannot be created. It is used by the generated code, as this
+// only happens when the registry does not match the annotations.
+func (r *Registry) MustNewClientInterceptor(index string, params map[string]string, route RouteInfo) grpcmw.ClientInterceptor {
+	intcp, err := r.NewClientInterceptor(index, params, route)
+	if err != nil {
+		panic(err)
+	}
+	return intcp
+}
+
+// GetClientInterceptor calls `GetClientInterceptor` on the `Default` registry.
+func GetClientInterceptor(index string) grpcmw.ClientInterceptor {
+	return Default.GetClientInterceptor(index)
+}
+
+// LookupClientInterceptor calls `LookupClientInterceptor` on the `Default`
+// registry.
+func LookupClientInterceptor(index string) (grpcmw.ClientInterceptor, bool) {
+	return Default.LookupClientInterceptor(index)
+}
+
+// SetClientInterceptor calls `SetClientInterceptor` on the `Default` registry.
+func SetClientInterceptor(index string, interceptor grpcmw.ClientInterceptor) {
+	Default.SetClientInterceptor(index, interceptor)
+}
+
+// DeleteClientInterceptor calls `DeleteClientInterceptor` on the `Default`
+// registry.
 func DeleteClientInterceptor(index string) {
-	clientLock.Lock()
-	defer clientLock.Unlock()
-	delete(clientRegistry, index)
+	Default.DeleteClientInterceptor(index)
+}
+
+// BindClientInterceptor calls `BindClientInterceptor` on the `Default`
+// registry.
+func BindClientInterceptor(index string) grpcmw.ClientInterceptor {
+	return Default.BindClientInterceptor(index)
+}
+
+// ListClient calls `ListClient` on the `Default` registry.
+func ListClient() []IndexInfo {
+	return Default.ListClient()
+}
+
+// SetClientFactory calls `SetClientFactory` on the `Default` registry.
+func SetClientFactory(index string, factory ClientFactory) error {
+	return Default.SetClientFactory(index, factory)
+}
+
+// DeleteClientFactory calls `DeleteClientFactory` on the `Default` registry.
+func DeleteClientFactory(index string) {
+	Default.DeleteClientFactory(index)
 }
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// recordingClientInterceptor returns an interceptor whose unary chain records
+// `name` in `calls`.
+func recordingClientInterceptor(calls *[]string, name string) grpcmw.ClientInterceptor {
+	return grpcmw.NewClientInterceptor(name).AddGRPCUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		*calls = append(*calls, name)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+func TestBindClientInterceptor(t *testing.T) {
+	var calls []string
+	r := New()
+	router := grpcmw.NewClientRouter()
+	router.GetRegister().Merge(r.BindClientInterceptor("auth"))
+	resolve := func() []string {
+		calls = nil
+		err := router.UnaryResolver()(context.Background(), "/pkg.Service/Method", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("resolver: %v", err)
+		}
+		return calls
+	}
+
+	r.SetClientInterceptor("auth", recordingClientInterceptor(&calls, "first"))
+	if got := resolve(); !reflect.DeepEqual(got, []string{"first"}) {
+		t.Errorf("resolver called %v, want [first]", got)
+	}
+	r.SetClientInterceptor("auth", recordingClientInterceptor(&calls, "second"))
+	if got := resolve(); !reflect.DeepEqual(got, []string{"second"}) {
+		t.Errorf("resolver called %v, want [second]", got)
+	}
+	r.DeleteClientInterceptor("auth")
+	if got := resolve(); len(got) != 0 {
+		t.Errorf("resolver called %v, want nothing", got)
+	}
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+)
+
+// RouteInfo identifies the level for which a factory creates interceptors.
+// Fields below the level are empty (e.g. `Method` for a service level).
+type RouteInfo struct {
+	Package string
+	Service string
+	Method  string
+	// Pattern is the route pattern of levels bound to a pattern (see
+	// `grpcmw.ServerRouter.AddPattern`), in which case the other fields are
+	// empty.
+	Pattern string
+}
+
+// String returns the route or pattern of the level. The service of a route
+// without package is not prefixed by any package (e.g. "/Health/Check").
+func (r RouteInfo) String() string {
+	service := r.Service
+	if r.Package != "" {
+		service = r.Package + "." + r.Service
+	}
+	switch {
+	case r.Pattern != "":
+		return r.Pattern
+	case r.Package == "" && r.Service == "":
+		return "global"
+	case r.Service == "":
+		return "/" + r.Package
+	case r.Method == "":
+		return "/" + service
+	}
+	return "/" + service + "/" + r.Method
+}
+
+// ServerFactory creates the server interceptors of an index for the level
+// described by `route`, configured with `params`.
+type ServerFactory func(params map[string]string, route RouteInfo) (grpcmw.ServerInterceptor, error)
+
+// ClientFactory creates the client interceptors of an index for the level
+// described by `route`, configured with `params`.
+type ClientFactory func(params map[string]string, route RouteInfo) (grpcmw.ClientInterceptor, error)
+
+// equalParams returns whether `a` and `b` hold the same parameters.
+func equalParams(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, exists := b[key]; !exists || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// errNoFactory returns the error reported when parameters are given to an
+// index that has no factory.
+func errNoFactory(index string) error {
+	return fmt.Errorf("Index %s has parameters but no factory", index)
+}
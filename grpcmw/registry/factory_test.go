@@ -0,0 +1,23 @@
+package registry
+
+import "testing"
+
+func TestRouteInfoString(t *testing.T) {
+	tests := []struct {
+		route RouteInfo
+		want  string
+	}{
+		{route: RouteInfo{}, want: "global"},
+		{route: RouteInfo{Package: "pb"}, want: "/pb"},
+		{route: RouteInfo{Package: "pb", Service: "S"}, want: "/pb.S"},
+		{route: RouteInfo{Package: "pb", Service: "S", Method: "M"}, want: "/pb.S/M"},
+		{route: RouteInfo{Service: "Health"}, want: "/Health"},
+		{route: RouteInfo{Service: "Health", Method: "Check"}, want: "/Health/Check"},
+		{route: RouteInfo{Pattern: "/*/List*"}, want: "/*/List*"},
+	}
+	for _, test := range tests {
+		if got := test.route.String(); got != test.want {
+			t.Errorf("%#v.String() = %q, want %q", test.route, got, test.want)
+		}
+	}
+}
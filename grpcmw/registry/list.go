@@ -0,0 +1,76 @@
+package registry
+
+import "sort"
+
+// Metadata describes what the interceptors of an index do, for the tools
+// listing the content of a registry.
+type Metadata struct {
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// IndexInfo describes an index of a registry for a given side, as returned by
+// `ListServer` and `ListClient`.
+type IndexInfo struct {
+	Index string `json:"index"`
+	Metadata
+	// Unary and Stream report whether interceptors of each type have been
+	// added at the index.
+	Unary  bool `json:"unary"`
+	Stream bool `json:"stream"`
+	// Factory reports whether a factory is registered at the index.
+	Factory bool `json:"factory"`
+	// Routes are the sorted routes of the levels for which the interceptors of
+	// the index have been requested (see `NewServerInterceptor`).
+	Routes []string `json:"routes,omitempty"`
+}
+
+// SetMetadata sets the metadata of `index`, for both server and client sides.
+// This is thread-safe.
+func (r *Registry) SetMetadata(index string, md Metadata) {
+	r.metadataLock.Lock()
+	defer r.metadataLock.Unlock()
+	r.metadata[index] = md
+}
+
+// GetMetadata returns the metadata of `index`, if any.
+// This is thread-safe.
+func (r *Registry) GetMetadata(index string) (md Metadata, exists bool) {
+	r.metadataLock.Lock()
+	defer r.metadataLock.Unlock()
+	md, exists = r.metadata[index]
+	return
+}
+
+// sortedInfos sets the metadata of `infos` and returns them sorted by index.
+func (r *Registry) sortedInfos(infos map[string]*IndexInfo) []IndexInfo {
+	r.metadataLock.Lock()
+	defer r.metadataLock.Unlock()
+	ret := make([]IndexInfo, 0, len(infos))
+	for index, info := range infos {
+		info.Metadata = r.metadata[index]
+		ret = append(ret, *info)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Index < ret[j].Index
+	})
+	return ret
+}
+
+// sortedRoutes returns the sorted routes (or indexes) of `routes`.
+func sortedRoutes(routes map[string]struct{}) []string {
+	if len(routes) == 0 {
+		return nil
+	}
+	ret := make([]string, 0, len(routes))
+	for route := range routes {
+		ret = append(ret, route)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// SetMetadata calls `SetMetadata` on the `Default` registry.
+func SetMetadata(index string, md Metadata) {
+	Default.SetMetadata(index, md)
+}
@@ -0,0 +1,63 @@
+// Package registry provides registries of server and client interceptors
+// indexed by name, which are used by the generated code to add the
+// interceptors referenced by annotations to routers.
+package registry
+
+import (
+	"sync"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+)
+
+// Registry holds server and client interceptors, or factories creating them,
+// at string indexes, along with their metadata. Registries are independent from
+// each other, so that differently configured routers can live in the same
+// process. All its methods are thread-safe.
+type Registry struct {
+	serverLock            *sync.Mutex
+	serverRegistry        map[string]grpcmw.ServerInterceptor
+	serverFactories       map[string]ServerFactory
+	serverRoutes          map[string]map[string]struct{}
+	serverBindings        map[string]grpcmw.ServerInterceptor
+	serverReferences      map[string]struct{}
+	serverFactoryLock     *sync.Mutex
+	serverFactoryBindings map[string]map[RouteInfo]*serverFactoryBinding
+	clientLock            *sync.Mutex
+	clientRegistry        map[string]grpcmw.ClientInterceptor
+	clientFactories       map[string]ClientFactory
+	clientRoutes          map[string]map[string]struct{}
+	clientBindings        map[string]grpcmw.ClientInterceptor
+	clientReferences      map[string]struct{}
+	clientFactoryLock     *sync.Mutex
+	clientFactoryBindings map[string]map[RouteInfo]*clientFactoryBinding
+	metadataLock          *sync.Mutex
+	metadata              map[string]Metadata
+}
+
+// Default is the registry used by the functions of this package and by the
+// generated code unless another registry is given.
+var Default = New()
+
+// New initializes an empty `Registry`.
+func New() *Registry {
+	return &Registry{
+		serverLock:            &sync.Mutex{},
+		serverRegistry:        make(map[string]grpcmw.ServerInterceptor),
+		serverFactories:       make(map[string]ServerFactory),
+		serverRoutes:          make(map[string]map[string]struct{}),
+		serverBindings:        make(map[string]grpcmw.ServerInterceptor),
+		serverReferences:      make(map[string]struct{}),
+		serverFactoryLock:     &sync.Mutex{},
+		serverFactoryBindings: make(map[string]map[RouteInfo]*serverFactoryBinding),
+		clientLock:            &sync.Mutex{},
+		clientRegistry:        make(map[string]grpcmw.ClientInterceptor),
+		clientFactories:       make(map[string]ClientFactory),
+		clientRoutes:          make(map[string]map[string]struct{}),
+		clientBindings:        make(map[string]grpcmw.ClientInterceptor),
+		clientReferences:      make(map[string]struct{}),
+		clientFactoryLock:     &sync.Mutex{},
+		clientFactoryBindings: make(map[string]map[RouteInfo]*clientFactoryBinding),
+		metadataLock:          &sync.Mutex{},
+		metadata:              make(map[string]Metadata),
+	}
+}
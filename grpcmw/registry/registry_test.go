@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+)
+
+func TestRegistryIsolation(t *testing.T) {
+	var calls []string
+	first, second := New(), New()
+	first.SetServerInterceptor("auth", recordingServerInterceptor(&calls, "first"))
+	second.SetServerInterceptor("auth", recordingServerInterceptor(&calls, "second"))
+	router := grpcmw.NewServerRouter()
+	router.GetRegister().Merge(first.BindServerInterceptor("auth"))
+
+	second.DeleteServerInterceptor("auth")
+	if got := resolveServerUnary(t, router, &calls, "/pkg.Service/Method"); len(got) != 1 || got[0] != "first" {
+		t.Errorf("resolver called %v, want [first]", got)
+	}
+	if _, exists := first.LookupServerInterceptor("auth"); !exists {
+		t.Error("deleting an index from a registry deleted it from another one")
+	}
+	if _, exists := LookupServerInterceptor("auth"); exists {
+		t.Error("an index registered in a registry is registered in Default")
+	}
+	first.GetClientInterceptor("auth")
+	if _, exists := second.LookupClientInterceptor("auth"); exists {
+		t.Error("an index created in a registry is registered in another one")
+	}
+}
@@ -1,44 +1,311 @@
 package registry
 
 import (
-	"sync"
+	"fmt"
 
 	"github.com/MarquisIO/go-grpcmw/grpcmw"
 )
 
-var (
-	serverLock     sync.Mutex
-	serverRegistry = make(map[string]grpcmw.ServerInterceptor)
-)
-
 // GetServerInterceptor returns the `grpcmw.ServerInterceptor` registered at
 // `index`. If nothing is at this `index`, it registers a new one using
 // `grpcmw.NewServerInterceptor` and returns it.
 // This is thread-safe.
-func GetServerInterceptor(index string) grpcmw.ServerInterceptor {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	intcp, ok := serverRegistry[index]
+func (r *Registry) GetServerInterceptor(index string) grpcmw.ServerInterceptor {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	intcp, ok := r.serverRegistry[index]
 	if !ok {
 		intcp = grpcmw.NewServerInterceptor(index)
-		serverRegistry[index] = intcp
+		r.setServerInterceptor(index, intcp)
 	}
 	return intcp
 }
 
+// LookupServerInterceptor returns the `grpcmw.ServerInterceptor` registered at
+// `index`, if any. Unlike `GetServerInterceptor`, it never registers a new one.
+// This is thread-safe.
+func (r *Registry) LookupServerInterceptor(index string) (interceptor grpcmw.ServerInterceptor, exists bool) {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	interceptor, exists = r.serverRegistry[index]
+	return
+}
+
 // SetServerInterceptor registers `interceptor` at `index`. It replaces any
-// interceptor that has been previously registered at this `index`.
+// interceptor that has been previously registered at this `index`, including
+// in the levels using it through `BindServerInterceptor`.
 // This is thread-safe.
-func SetServerInterceptor(index string, interceptor grpcmw.ServerInterceptor) {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	serverRegistry[index] = interceptor
+func (r *Registry) SetServerInterceptor(index string, interceptor grpcmw.ServerInterceptor) {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	r.setServerInterceptor(index, interceptor)
+}
+
+// setServerInterceptor registers `interceptor` at `index` and binds the binding
+// of `index` to it, if any. It must be called with the lock held.
+func (r *Registry) setServerInterceptor(index string, interceptor grpcmw.ServerInterceptor) {
+	r.serverRegistry[index] = interceptor
+	if binding, exists := r.serverBindings[index]; exists {
+		bindServerInterceptor(binding, index, interceptor)
+	}
+}
+
+// DeleteServerInterceptor deletes any interceptor registered at `index`. The
+// levels using it through `BindServerInterceptor` stop calling it.
+// This is thread-safe.
+func (r *Registry) DeleteServerInterceptor(index string) {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	delete(r.serverRegistry, index)
+	if binding, exists := r.serverBindings[index]; exists {
+		unbindServerInterceptor(binding, index)
+	}
+}
+
+// BindServerInterceptor returns a `grpcmw.ServerInterceptor` indexed by `index`
+// whose chains reference those of the interceptor registered at `index`,
+// whichever it is: replacing it with `SetServerInterceptor` or deleting it with
+// `DeleteServerInterceptor` takes effect everywhere the returned interceptor is
+// merged, without rebuilding the routers. The same interceptor is returned for
+// each call with the same `index`. Frozen routers keep the interceptors it
+// referenced when they have been frozen.
+// This is thread-safe.
+func (r *Registry) BindServerInterceptor(index string) grpcmw.ServerInterceptor {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	binding, exists := r.serverBindings[index]
+	if !exists {
+		binding = grpcmw.NewServerInterceptor(index)
+		r.serverBindings[index] = binding
+		if interceptor, registered := r.serverRegistry[index]; registered {
+			bindServerInterceptor(binding, index, interceptor)
+		}
+	}
+	return binding
+}
+
+// bindServerInterceptor makes the chains of `binding` reference those of
+// `interceptor` under `index`, replacing any previous reference. Frozen chains
+// are left untouched.
+func bindServerInterceptor(binding grpcmw.ServerInterceptor, index string, interceptor grpcmw.ServerInterceptor) {
+	if unaries := binding.UnaryServerInterceptor(); !unaries.Frozen() {
+		unaries.AddNamedInterceptor(index, interceptor.UnaryServerInterceptor())
+	}
+	if streams := binding.StreamServerInterceptor(); !streams.Frozen() {
+		streams.AddNamedInterceptor(index, interceptor.StreamServerInterceptor())
+	}
+}
+
+// unbindServerInterceptor removes the reference held by the chains of `binding`
+// under `index`. Frozen chains are left untouched.
+func unbindServerInterceptor(binding grpcmw.ServerInterceptor, index string) {
+	if unaries := binding.UnaryServerInterceptor(); !unaries.Frozen() {
+		unaries.Remove(index)
+	}
+	if streams := binding.StreamServerInterceptor(); !streams.Frozen() {
+		streams.Remove(index)
+	}
+}
+
+// serverFactoryBinding is an interceptor created by the factory of an index for
+// a route with `params`, whose chains reference the ones created by the
+// factory so that they can be created again when the factory is replaced.
+type serverFactoryBinding struct {
+	params  map[string]string
+	binding grpcmw.ServerInterceptor
+}
+
+// SetServerFactory registers `factory` at `index`. It replaces any factory that
+// has been previously registered at this `index`. Interceptors created with
+// `NewServerInterceptor` at this `index` are created by `factory` instead of
+// being the ones registered at this `index`.
+//
+// The interceptors created by the previous factories for each route (see
+// `NewServerInterceptor`) are created again by `factory`, with the same
+// parameters, and replaced everywhere they have been merged, without
+// rebuilding the routers. It fails without registering
+// `factory` if it fails for any of them. Frozen routers keep the interceptors
+// they have been frozen with. Factories must not call `NewServerInterceptor`,
+// `SetServerFactory` or `DeleteServerFactory`.
+// This is thread-safe.
+func (r *Registry) SetServerFactory(index string, factory ServerFactory) error {
+	r.serverFactoryLock.Lock()
+	defer r.serverFactoryLock.Unlock()
+	bindings := r.serverFactoryBindings[index]
+	created := make(map[RouteInfo]grpcmw.ServerInterceptor, len(bindings))
+	for route, b := range bindings {
+		intcp, err := factory(b.params, route)
+		if err != nil {
+			return fmt.Errorf("Index %s for %s: %v", index, route, err)
+		}
+		created[route] = intcp
+	}
+	r.serverLock.Lock()
+	r.serverFactories[index] = factory
+	r.serverLock.Unlock()
+	for route, b := range bindings {
+		bindServerInterceptor(b.binding, index, created[route])
+	}
+	return nil
+}
+
+// LookupServerFactory returns the factory registered at `index`, if any.
+// This is thread-safe.
+func (r *Registry) LookupServerFactory(index string) (factory ServerFactory, exists bool) {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	factory, exists = r.serverFactories[index]
+	return
+}
+
+// DeleteServerFactory deletes any factory registered at `index`. The
+// interceptors it has created are removed from everywhere they have been
+// merged, until another factory is registered at `index`.
+// This is thread-safe.
+func (r *Registry) DeleteServerFactory(index string) {
+	r.serverFactoryLock.Lock()
+	defer r.serverFactoryLock.Unlock()
+	r.serverLock.Lock()
+	delete(r.serverFactories, index)
+	r.serverLock.Unlock()
+	for _, b := range r.serverFactoryBindings[index] {
+		unbindServerInterceptor(b.binding, index)
+	}
 }
 
-// DeleteServerInterceptor deletes any interceptor registered at `index`.
+// NewServerInterceptor returns the interceptors of `index` for the level
+// described by `route`. If a factory is registered at `index`, they are created
+// by the factory with `params` and bound under `index`, so that they are
+// created again when the factory is replaced (see `SetServerFactory`). Only the
+// interceptors created last for each route are tracked this way, so that
+// rebuilding or reloading routers does not accumulate them: they are shared by
+// the levels of the same route as long as `params` do not change. Otherwise,
+// `params` must be empty and they are the ones registered at `index`, bound
+// with `BindServerInterceptor` so that they can be replaced later on. Once they
+// have been created, the route is recorded as referencing `index` (see
+// `ListServer`).
 // This is thread-safe.
+func (r *Registry) NewServerInterceptor(index string, params map[string]string, route RouteInfo) (grpcmw.ServerInterceptor, error) {
+	r.serverFactoryLock.Lock()
+	defer r.serverFactoryLock.Unlock()
+	factory, exists := r.LookupServerFactory(index)
+	if !exists {
+		if len(params) > 0 {
+			return nil, errNoFactory(index)
+		}
+		r.referenceServerInterceptor(index, route)
+		return r.BindServerInterceptor(index), nil
+	}
+	bindings, exists := r.serverFactoryBindings[index]
+	if !exists {
+		bindings = make(map[RouteInfo]*serverFactoryBinding)
+		r.serverFactoryBindings[index] = bindings
+	}
+	if b, exists := bindings[route]; exists && equalParams(b.params, params) {
+		return b.binding, nil
+	}
+	created, err := factory(params, route)
+	if err != nil {
+		return nil, fmt.Errorf("Index %s for %s: %v", index, route, err)
+	}
+	r.referenceServerInterceptor(index, route)
+	binding := grpcmw.NewServerInterceptor(index)
+	bindServerInterceptor(binding, index, created)
+	bindings[route] = &serverFactoryBinding{
+		params:  params,
+		binding: binding,
+	}
+	return binding, nil
+}
+
+// referenceServerInterceptor records that `route` references `index`.
+func (r *Registry) referenceServerInterceptor(index string, route RouteInfo) {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	routes, exists := r.serverRoutes[index]
+	if !exists {
+		routes = make(map[string]struct{})
+		r.serverRoutes[index] = routes
+	}
+	routes[route.String()] = struct{}{}
+}
+
+// ListServer returns the description of the indexes at which server
+// interceptors or factories are registered, sorted by index.
+// This is thread-safe.
+func (r *Registry) ListServer() []IndexInfo {
+	r.serverLock.Lock()
+	infos := make(map[string]*IndexInfo, len(r.serverRegistry)+len(r.serverFactories))
+	for index, intcp := range r.serverRegistry {
+		infos[index] = &IndexInfo{
+			Index:  index,
+			Unary:  len(intcp.UnaryServerInterceptor().Describe()) > 0,
+			Stream: len(intcp.StreamServerInterceptor().Describe()) > 0,
+		}
+	}
+	for index := range r.serverFactories {
+		if _, exists := infos[index]; !exists {
+			infos[index] = &IndexInfo{Index: index}
+		}
+		infos[index].Factory = true
+	}
+	for index, info := range infos {
+		info.Routes = sortedRoutes(r.serverRoutes[index])
+	}
+	r.serverLock.Unlock()
+	return r.sortedInfos(infos)
+}
+
+// MustNewServerInterceptor is like `NewServerInterceptor` but panics if the
+// interceptors cannot be created. It is used by the generated code, as this
+// only happens when the registry does not match the annotations.
+func (r *Registry) MustNewServerInterceptor(index string, params map[string]string, route RouteInfo) grpcmw.ServerInterceptor {
+	intcp, err := r.NewServerInterceptor(index, params, route)
+	if err != nil {
+		panic(err)
+	}
+	return intcp
+}
+
+// GetServerInterceptor calls `GetServerInterceptor` on the `Default` registry.
+func GetServerInterceptor(index string) grpcmw.ServerInterceptor {
+	return Default.GetServerInterceptor(index)
+}
+
+// LookupServerInterceptor calls `LookupServerInterceptor` on the `Default`
+// registry.
+func LookupServerInterceptor(index string) (grpcmw.ServerInterceptor, bool) {
+	return Default.LookupServerInterceptor(index)
+}
+
+// SetServerInterceptor calls `SetServerInterceptor` on the `Default` registry.
+func SetServerInterceptor(index string, interceptor grpcmw.ServerInterceptor) {
+	Default.SetServerInterceptor(index, interceptor)
+}
+
+// DeleteServerInterceptor calls `DeleteServerInterceptor` on the `Default`
+// registry.
 func DeleteServerInterceptor(index string) {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	delete(serverRegistry, index)
+	Default.DeleteServerInterceptor(index)
+}
+
+// BindServerInterceptor calls `BindServerInterceptor` on the `Default`
+// registry.
+func BindServerInterceptor(index string) grpcmw.ServerInterceptor {
+	return Default.BindServerInterceptor(index)
+}
+
+// ListServer calls `ListServer` on the `Default` registry.
+func ListServer() []IndexInfo {
+	return Default.ListServer()
+}
+
+// SetServerFactory calls `SetServerFactory` on the `Default` registry.
+func SetServerFactory(index string, factory ServerFactory) error {
+	return Default.SetServerFactory(index, factory)
+}
+
+// DeleteServerFactory calls `DeleteServerFactory` on the `Default` registry.
+func DeleteServerFactory(index string) {
+	Default.DeleteServerFactory(index)
 }
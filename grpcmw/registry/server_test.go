@@ -0,0 +1,255 @@
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MarquisIO/go-grpcmw/grpcmw"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// recordingServerInterceptor returns an interceptor whose unary chain records
+// `name` in `calls`.
+func recordingServerInterceptor(calls *[]string, name string) grpcmw.ServerInterceptor {
+	return grpcmw.NewServerInterceptor(name).AddGRPCUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	})
+}
+
+// recordingServerFactory returns a factory whose interceptors record `name`
+// in `calls`, and counts how many times it has been called in `created`.
+func recordingServerFactory(calls *[]string, created *int, name string) ServerFactory {
+	return func(params map[string]string, route RouteInfo) (grpcmw.ServerInterceptor, error) {
+		*created++
+		if params["fail"] == name {
+			return nil, errors.New("failed")
+		}
+		return recordingServerInterceptor(calls, name), nil
+	}
+}
+
+// resolveServerUnary calls the unary resolver of `router` for `route` and
+// returns the names recorded in `calls` by the interceptors it called.
+func resolveServerUnary(t *testing.T, router grpcmw.ServerRouter, calls *[]string, route string) []string {
+	*calls = nil
+	_, err := router.UnaryResolver()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: route}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("%s: %v", route, err)
+	}
+	return *calls
+}
+
+func TestServerFactoryReload(t *testing.T) {
+	var calls []string
+	var created int
+	r := New()
+	if err := r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "old")); err != nil {
+		t.Fatalf("SetServerFactory() = %v", err)
+	}
+	route := RouteInfo{Package: "pkg", Service: "Service"}
+	build := func(staging grpcmw.ServerRouter) error {
+		pkg, _ := staging.RegisterPackage(route.Package)
+		service := grpcmw.NewServerInterceptorRegister(route.Service)
+		pkg.Register(service)
+		intcp, err := r.NewServerInterceptor("auth", map[string]string{"scope": "read"}, route)
+		if err != nil {
+			return err
+		}
+		service.Merge(intcp)
+		return nil
+	}
+
+	router := grpcmw.NewServerRouter()
+	for i := 0; i < 100; i++ {
+		if err := router.Reload(build); err != nil {
+			t.Fatalf("Reload() = %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("the factory has been called %d times for 100 reloads of the same route, want 1", created)
+	}
+	if got := resolveServerUnary(t, router, &calls, "/pkg.Service/Method"); len(got) != 1 || got[0] != "old" {
+		t.Fatalf("resolver called %v, want [old]", got)
+	}
+
+	created = 0
+	if err := r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "new")); err != nil {
+		t.Fatalf("SetServerFactory() = %v", err)
+	}
+	if created != 1 {
+		t.Fatalf("the replacing factory has been called %d times, want 1", created)
+	}
+	if got := resolveServerUnary(t, router, &calls, "/pkg.Service/Method"); len(got) != 1 || got[0] != "new" {
+		t.Fatalf("resolver called %v, want [new]", got)
+	}
+}
+
+func TestBindServerInterceptor(t *testing.T) {
+	var calls []string
+	r := New()
+	router := grpcmw.NewServerRouter()
+	router.GetRegister().Merge(r.BindServerInterceptor("auth"))
+	pkg, _ := router.RegisterPackage("pkg")
+	service := grpcmw.NewServerInterceptorRegister("Service")
+	pkg.Register(service)
+	service.Register(grpcmw.NewServerInterceptor("Method"))
+	if r.BindServerInterceptor("auth") != r.BindServerInterceptor("auth") {
+		t.Fatal("BindServerInterceptor() returned different interceptors for the same index")
+	}
+
+	steps := []struct {
+		name   string
+		modify func()
+		want   []string
+	}{
+		{
+			name:   "Unregistered",
+			modify: func() {},
+			want:   nil,
+		},
+		{
+			name: "GetServerInterceptor",
+			modify: func() {
+				r.GetServerInterceptor("auth").Merge(recordingServerInterceptor(&calls, "first"))
+			},
+			want: []string{"first"},
+		},
+		{
+			name: "SetServerInterceptor",
+			modify: func() {
+				r.SetServerInterceptor("auth", recordingServerInterceptor(&calls, "second"))
+			},
+			want: []string{"second"},
+		},
+		{
+			name: "DeleteServerInterceptor",
+			modify: func() {
+				r.DeleteServerInterceptor("auth")
+			},
+			want: nil,
+		},
+		{
+			name: "SetServerInterceptor after Freeze",
+			modify: func() {
+				r.SetServerInterceptor("auth", recordingServerInterceptor(&calls, "frozen"))
+				router.Freeze(grpcmw.FreezeError)
+				r.SetServerInterceptor("auth", recordingServerInterceptor(&calls, "ignored"))
+			},
+			want: []string{"frozen"},
+		},
+	}
+	for _, step := range steps {
+		step.modify()
+		if got := resolveServerUnary(t, router, &calls, "/pkg.Service/Method"); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: resolver called %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestServerFactoryRebind(t *testing.T) {
+	var calls []string
+	var created int
+	r := New()
+	router := grpcmw.NewServerRouter()
+	if err := r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "first")); err != nil {
+		t.Fatalf("SetServerFactory() = %v", err)
+	}
+	router.GetRegister().Merge(r.MustNewServerInterceptor("auth", map[string]string{"fail": "third"}, RouteInfo{}))
+
+	steps := []struct {
+		name   string
+		modify func() error
+		fails  bool
+		want   []string
+	}{
+		{
+			name:   "NewServerInterceptor",
+			modify: func() error { return nil },
+			want:   []string{"first"},
+		},
+		{
+			name: "SetServerFactory",
+			modify: func() error {
+				return r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "second"))
+			},
+			want: []string{"second"},
+		},
+		{
+			name: "SetServerFactory failing",
+			modify: func() error {
+				return r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "third"))
+			},
+			fails: true,
+			want:  []string{"second"},
+		},
+		{
+			name: "DeleteServerFactory",
+			modify: func() error {
+				r.DeleteServerFactory("auth")
+				return nil
+			},
+			want: nil,
+		},
+		{
+			name: "SetServerFactory after DeleteServerFactory",
+			modify: func() error {
+				return r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "fourth"))
+			},
+			want: []string{"fourth"},
+		},
+	}
+	for _, step := range steps {
+		if err := step.modify(); (err != nil) != step.fails {
+			t.Errorf("%s: error = %v, want failure %v", step.name, err, step.fails)
+		}
+		if got := resolveServerUnary(t, router, &calls, "/pkg.Service/Method"); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: resolver called %v, want %v", step.name, got, step.want)
+		}
+	}
+	if _, factory := r.LookupServerFactory("auth"); !factory {
+		t.Error("LookupServerFactory() found no factory")
+	}
+	if _, err := r.NewServerInterceptor("logging", map[string]string{"level": "debug"}, RouteInfo{}); err == nil {
+		t.Error("NewServerInterceptor() accepted parameters for an index without factory")
+	}
+}
+
+func TestListServer(t *testing.T) {
+	var calls []string
+	var created int
+	r := New()
+	r.SetServerInterceptor("logging", recordingServerInterceptor(&calls, "logging"))
+	if err := r.SetServerFactory("auth", recordingServerFactory(&calls, &created, "auth")); err != nil {
+		t.Fatalf("SetServerFactory() = %v", err)
+	}
+	r.SetMetadata("auth", Metadata{Description: "Checks the credentials", Owner: "security"})
+	r.MustNewServerInterceptor("auth", nil, RouteInfo{Package: "pkg", Service: "Service"})
+	r.MustNewServerInterceptor("auth", nil, RouteInfo{Package: "pkg"})
+	r.MustNewServerInterceptor("logging", nil, RouteInfo{Service: "Health"})
+	r.MustNewServerInterceptor("logging", nil, RouteInfo{Service: "Health"})
+
+	want := []IndexInfo{
+		{
+			Index:    "auth",
+			Metadata: Metadata{Description: "Checks the credentials", Owner: "security"},
+			Factory:  true,
+			Routes:   []string{"/pkg", "/pkg.Service"},
+		},
+		{
+			Index:  "logging",
+			Unary:  true,
+			Routes: []string{"/Health"},
+		},
+	}
+	if got := r.ListServer(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListServer() = %+v, want %+v", got, want)
+	}
+	if got := r.ListClient(); len(got) != 0 {
+		t.Errorf("ListClient() = %+v, want nothing", got)
+	}
+}
@@ -0,0 +1,183 @@
+package registry
+
+import "strings"
+
+// ReferenceServer records that server interceptors are expected at `indexes` in
+// the registry. It is called by the generated code for the indexes referenced
+// by annotations when they are registered with the registry (see
+// `RegisterServerInterceptorsWithRegistry`), so that `ValidateServer` can report
+// those that have never been populated.
+// This is thread-safe.
+func (r *Registry) ReferenceServer(indexes ...string) {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	for _, index := range indexes {
+		r.serverReferences[index] = struct{}{}
+	}
+}
+
+// ReferencedServer returns the sorted indexes recorded with `ReferenceServer`.
+// This is thread-safe.
+func (r *Registry) ReferencedServer() []string {
+	r.serverLock.Lock()
+	defer r.serverLock.Unlock()
+	return sortedRoutes(r.serverReferences)
+}
+
+// ReferenceClient records that client interceptors are expected at `indexes` in
+// the registry. It is called by the generated code for the indexes referenced
+// by annotations when they are registered with the registry (see
+// `RegisterClientInterceptorsWithRegistry`), so that `ValidateClient` can report
+// those that have never been populated.
+// This is thread-safe.
+func (r *Registry) ReferenceClient(indexes ...string) {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	for _, index := range indexes {
+		r.clientReferences[index] = struct{}{}
+	}
+}
+
+// ReferencedClient returns the sorted indexes recorded with `ReferenceClient`.
+// This is thread-safe.
+func (r *Registry) ReferencedClient() []string {
+	r.clientLock.Lock()
+	defer r.clientLock.Unlock()
+	return sortedRoutes(r.clientReferences)
+}
+
+// ReferenceServer calls `ReferenceServer` on the `Default` registry.
+func ReferenceServer(indexes ...string) {
+	Default.ReferenceServer(indexes...)
+}
+
+// ReferencedServer calls `ReferencedServer` on the `Default` registry.
+func ReferencedServer() []string {
+	return Default.ReferencedServer()
+}
+
+// ReferenceClient calls `ReferenceClient` on the `Default` registry.
+func ReferenceClient(indexes ...string) {
+	Default.ReferenceClient(indexes...)
+}
+
+// ReferencedClient calls `ReferencedClient` on the `Default` registry.
+func ReferencedClient() []string {
+	return Default.ReferencedClient()
+}
+
+// ValidationError reports the indexes at which no interceptor has been
+// registered, for each side.
+type ValidationError struct {
+	Server []string
+	Client []string
+}
+
+// Error implements the `error` interface.
+func (e *ValidationError) Error() string {
+	var msgs []string
+	if len(e.Server) > 0 {
+		msgs = append(msgs, "server: "+strings.Join(e.Server, ", "))
+	}
+	if len(e.Client) > 0 {
+		msgs = append(msgs, "client: "+strings.Join(e.Client, ", "))
+	}
+	return "No interceptor registered at indexes (" + strings.Join(msgs, "; ") + ")"
+}
+
+// MissingServerInterceptors returns the indexes among `indexes` at which no
+// server interceptor nor factory has been added, either because nothing is
+// registered at them or because only an empty one has been created by
+// `GetServerInterceptor`. Each index is reported once.
+// This is thread-safe.
+func (r *Registry) MissingServerInterceptors(indexes ...string) (missing []string) {
+	seen := make(map[string]struct{}, len(indexes))
+	for _, index := range indexes {
+		if _, dup := seen[index]; dup {
+			continue
+		}
+		seen[index] = struct{}{}
+		if _, factory := r.LookupServerFactory(index); factory {
+			continue
+		}
+		intcp, exists := r.LookupServerInterceptor(index)
+		if !exists || len(intcp.UnaryServerInterceptor().Describe())+len(intcp.StreamServerInterceptor().Describe()) == 0 {
+			missing = append(missing, index)
+		}
+	}
+	return
+}
+
+// MissingClientInterceptors returns the indexes among `indexes` at which no
+// client interceptor nor factory has been added, either because nothing is
+// registered at them or because only an empty one has been created by
+// `GetClientInterceptor`. Each index is reported once.
+// This is thread-safe.
+func (r *Registry) MissingClientInterceptors(indexes ...string) (missing []string) {
+	seen := make(map[string]struct{}, len(indexes))
+	for _, index := range indexes {
+		if _, dup := seen[index]; dup {
+			continue
+		}
+		seen[index] = struct{}{}
+		if _, factory := r.LookupClientFactory(index); factory {
+			continue
+		}
+		intcp, exists := r.LookupClientInterceptor(index)
+		if !exists || len(intcp.UnaryClientInterceptor().Describe())+len(intcp.StreamClientInterceptor().Describe()) == 0 {
+			missing = append(missing, index)
+		}
+	}
+	return
+}
+
+// ValidateServer returns a `*ValidationError` if no server interceptor has
+// been added at some of the indexes referenced by the generated server code
+// (see `ReferenceServer`), so that a typo in an annotation is not silently
+// ignored.
+func (r *Registry) ValidateServer() error {
+	if missing := r.MissingServerInterceptors(r.ReferencedServer()...); len(missing) > 0 {
+		return &ValidationError{Server: missing}
+	}
+	return nil
+}
+
+// ValidateClient returns a `*ValidationError` if no client interceptor has
+// been added at some of the indexes referenced by the generated client code
+// (see `ReferenceClient`), so that a typo in an annotation is not silently
+// ignored.
+func (r *Registry) ValidateClient() error {
+	if missing := r.MissingClientInterceptors(r.ReferencedClient()...); len(missing) > 0 {
+		return &ValidationError{Client: missing}
+	}
+	return nil
+}
+
+// Validate returns a `*ValidationError` if no server or no client interceptor
+// has been added at some of the indexes referenced by the generated server or
+// client code respectively (see `ReferenceServer` and `ReferenceClient`).
+func (r *Registry) Validate() error {
+	err := &ValidationError{
+		Server: r.MissingServerInterceptors(r.ReferencedServer()...),
+		Client: r.MissingClientInterceptors(r.ReferencedClient()...),
+	}
+	if len(err.Server) > 0 || len(err.Client) > 0 {
+		return err
+	}
+	return nil
+}
+
+// ValidateServer calls `ValidateServer` on the `Default` registry.
+func ValidateServer() error {
+	return Default.ValidateServer()
+}
+
+// ValidateClient calls `ValidateClient` on the `Default` registry.
+func ValidateClient() error {
+	return Default.ValidateClient()
+}
+
+// Validate calls `Validate` on the `Default` registry.
+func Validate() error {
+	return Default.Validate()
+}
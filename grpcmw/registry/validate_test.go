@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestValidateSides(t *testing.T) {
+	r := New()
+	r.ReferenceServer("auth", "logging")
+	r.GetServerInterceptor("auth").AddGRPCUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	})
+
+	err, ok := r.ValidateServer().(*ValidationError)
+	if !ok || len(err.Server) != 1 || err.Server[0] != "logging" || len(err.Client) != 0 {
+		t.Fatalf("ValidateServer() = %v, want the server index logging", err)
+	}
+	if err := r.ValidateClient(); err != nil {
+		t.Fatalf("ValidateClient() = %v, want no error for server references", err)
+	}
+	if err, ok := r.Validate().(*ValidationError); !ok || len(err.Server) != 1 || len(err.Client) != 0 {
+		t.Fatalf("Validate() = %v, want the server index logging only", err)
+	}
+	for _, index := range ReferencedServer() {
+		if index == "logging" {
+			t.Fatal("references recorded in a registry leak into Default")
+		}
+	}
+}
+
+func TestMissingServerInterceptors(t *testing.T) {
+	var calls []string
+	var created int
+	r := New()
+	r.GetServerInterceptor("empty")
+	r.SetServerInterceptor("auth", recordingServerInterceptor(&calls, "auth"))
+	if err := r.SetServerFactory("ratelimit", recordingServerFactory(&calls, &created, "ratelimit")); err != nil {
+		t.Fatalf("SetServerFactory() = %v", err)
+	}
+
+	missing := r.MissingServerInterceptors("auth", "empty", "ratelimit", "auht", "empty")
+	if want := []string{"empty", "auht"}; !equalIndexes(missing, want) {
+		t.Errorf("MissingServerInterceptors() = %v, want %v", missing, want)
+	}
+	if missing := r.MissingClientInterceptors("auth"); !equalIndexes(missing, []string{"auth"}) {
+		t.Errorf("MissingClientInterceptors() = %v, want the server index auth", missing)
+	}
+}
+
+// equalIndexes returns whether `a` and `b` hold the same indexes in the same
+// order.
+func equalIndexes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,244 @@
 package grpcmw
 
-import "regexp"
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// routeLevelsSize is the number of levels on the path of a route that routers
+// compute without allocating (see `routeLevels`), i.e. the levels of a package
+// of up to six segments with `WithNestedPackages`, of its service and of its
+// method.
+const routeLevelsSize = 8
 
 var (
-	routeRegexp = regexp.MustCompile(`\/(?:(.+)\.)?(.+)\/(.+)`)
+	errInvalidRoute = errors.New("Invalid route")
+
+	// routesGeneration is incremented each time an interceptor chain or a
+	// level is modified so that routers know their compiled routes are stale.
+	routesGeneration uint64
 )
+
+// invalidateRoutes marks every route compiled by any router as stale.
+func invalidateRoutes() {
+	atomic.AddUint64(&routesGeneration, 1)
+}
+
+// currentRoutesGeneration returns the generation that routes compiled from now
+// on belong to.
+func currentRoutesGeneration() uint64 {
+	return atomic.LoadUint64(&routesGeneration)
+}
+
+// routeLevels appends the indexes of the levels on the path of `route` (e.g.
+// "/pkg.Service/Method") to `levels`, from the package level to the method
+// level (see `parseRoute`). If `nested` is true, each segment of the package is
+// a level of its own. The package level of a route without package is indexed
+// by an empty string. It does not allocate if `levels` has enough capacity.
+func routeLevels(levels []string, route string, nested bool) ([]string, error) {
+	pkg, service, method, err := parseRoute(route)
+	if err != nil {
+		return levels, err
+	}
+	return append(packageLevels(levels, pkg, nested), service, method), nil
+}
+
+// parseRoute splits `route` into its package, service and method, following
+// the grammar of the routes of gRPC:
+//
+//	route   = "/" service "/" method
+//	service = [ package "." ] name
+//	package = segment { "." segment }
+//
+// where `name`, `method` and segments are not empty and `method` is the only
+// one that may contain dots. The package is empty if the service has no dot.
+// Routes that do not follow this grammar (e.g. with a trailing slash, an empty
+// segment or a missing method) are rejected. It never allocates.
+func parseRoute(route string) (pkg, service, method string, err error) {
+	fullService, method, ok := splitRoute(route)
+	if !ok {
+		return "", "", "", errInvalidRoute
+	}
+	idx := strings.LastIndexByte(fullService, '.')
+	if idx < 0 {
+		return "", fullService, method, nil
+	}
+	pkg, service = fullService[:idx], fullService[idx+1:]
+	if len(service) == 0 || len(pkg) == 0 || pkg[0] == '.' || pkg[len(pkg)-1] == '.' || strings.Contains(pkg, "..") {
+		return "", "", "", errInvalidRoute
+	}
+	return pkg, service, method, nil
+}
+
+// packageLevels appends the indexes of the levels of the protobuf package `pkg`
+// to `levels`. If `nested` is true, each segment of the package is a level of
+// its own, indexed by the package it stands for: "company.billing.v1" goes
+// through the levels "company", "company.billing" and "company.billing.v1". As
+// service names have no dot, the levels of subpackages never share their index
+// with the levels of services.
+func packageLevels(levels []string, pkg string, nested bool) []string {
+	if !nested {
+		return append(levels, pkg)
+	}
+	for idx := 0; idx < len(pkg); idx++ {
+		if pkg[idx] == '.' {
+			levels = append(levels, pkg[:idx])
+		}
+	}
+	return append(levels, pkg)
+}
+
+// pathPackages returns the number of package levels at the beginning of
+// `path`: the first index and then each index that extends the previous one
+// with a segment (see `packageLevels`).
+func pathPackages(path []string) int {
+	if len(path) == 0 {
+		return 0
+	}
+	count := 1
+	for ; count < len(path); count++ {
+		parent, sub := path[count-1], path[count]
+		if len(sub) <= len(parent) || sub[len(parent)] != '.' || !strings.HasPrefix(sub, parent) {
+			break
+		}
+	}
+	return count
+}
+
+// levelsRoute is the opposite of `routeLevels`: it builds the route of the
+// method level at the end of `path`.
+func levelsRoute(path []string) string {
+	last := len(path) - 1
+	return "/" + serviceName(path[last-2], path[last-1]) + "/" + path[last]
+}
+
+// serviceName returns the full name of the service `service` of the package
+// `pkg`, which may be empty.
+func serviceName(pkg, service string) string {
+	if len(pkg) == 0 {
+		return service
+	}
+	return pkg + "." + service
+}
+
+// levelName returns a readable name for the level at `path`: "global" for the
+// global level, the package for package levels, the full name of the service
+// for service levels and the route of the method for method levels.
+func levelName(path []string) string {
+	packages := pathPackages(path)
+	switch {
+	case len(path) == 0:
+		return "global"
+	case len(path) <= packages:
+		return path[len(path)-1]
+	case len(path) == packages+1:
+		return serviceName(path[packages-1], path[packages])
+	}
+	return levelsRoute(path[:packages+2])
+}
+
+// levelExclusions returns, for each of the `count` levels of a route, the set
+// of names excluded by the levels that come after it. The first `paths` levels
+// are the levels of the path of the route, and the others are bound to patterns
+// matching it: the latter come after the former, but are also subject to the
+// names excluded by any level of the path. `excluded` returns the names
+// excluded by the level at `idx`.
+func levelExclusions(count, paths int, excluded func(idx int) []string) []map[string]struct{} {
+	exclusions := make([]map[string]struct{}, count)
+	var current map[string]struct{}
+	for idx := count - 1; idx >= 0; idx-- {
+		exclusions[idx] = current
+		current = mergeExclusions(current, excluded(idx))
+	}
+	var path []string
+	for idx := 0; idx < paths; idx++ {
+		path = append(path, excluded(idx)...)
+	}
+	for idx := paths; idx < count; idx++ {
+		exclusions[idx] = mergeExclusions(exclusions[idx], path)
+	}
+	return exclusions
+}
+
+// mergeExclusions returns the union of `current` and `names`. `current` is
+// returned as is if `names` is empty, and is never modified.
+func mergeExclusions(current map[string]struct{}, names []string) map[string]struct{} {
+	if len(names) == 0 {
+		return current
+	}
+	merged := make(map[string]struct{}, len(current)+len(names))
+	for name := range current {
+		merged[name] = struct{}{}
+	}
+	for _, name := range names {
+		merged[name] = struct{}{}
+	}
+	return merged
+}
+
+// routePattern is a precompiled route pattern such as "/pkg.*/Get*", in which
+// `*` matches any sequence of characters except '/'.
+type routePattern struct {
+	pattern string
+	service globPattern
+	method  globPattern
+}
+
+// globPattern holds the literal parts of a glob pattern that are separated by
+// `*`.
+type globPattern []string
+
+// compileRoutePattern splits `pattern` into the glob patterns of the service
+// and of the method so that matching a route never allocates.
+func compileRoutePattern(pattern string) (*routePattern, error) {
+	service, method, ok := splitRoute(pattern)
+	if !ok {
+		return nil, errors.New("Invalid route pattern")
+	}
+	return &routePattern{
+		pattern: pattern,
+		service: strings.Split(service, "*"),
+		method:  strings.Split(method, "*"),
+	}, nil
+}
+
+// splitRoute splits `route` (e.g. "/pkg.Service/Method") into the full name of
+// its service and the name of its method, which are both non-empty and do not
+// contain any '/'.
+func splitRoute(route string) (service, method string, ok bool) {
+	if len(route) == 0 || route[0] != '/' {
+		return "", "", false
+	}
+	idx := strings.IndexByte(route[1:], '/')
+	if idx <= 0 || idx == len(route)-2 || strings.IndexByte(route[idx+2:], '/') >= 0 {
+		return "", "", false
+	}
+	return route[1 : idx+1], route[idx+2:], true
+}
+
+// match returns whether `route` matches the pattern.
+func (p *routePattern) match(route string) bool {
+	service, method, ok := splitRoute(route)
+	return ok && p.service.match(service) && p.method.match(method)
+}
+
+// match returns whether `s` matches the glob pattern.
+func (g globPattern) match(s string) bool {
+	if len(g) == 1 {
+		return s == g[0]
+	}
+	if !strings.HasPrefix(s, g[0]) {
+		return false
+	}
+	s = s[len(g[0]):]
+	for _, part := range g[1 : len(g)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	last := g[len(g)-1]
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
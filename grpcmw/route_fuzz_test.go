@@ -0,0 +1,42 @@
+//go:build go1.18
+// +build go1.18
+
+package grpcmw
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseRoute(f *testing.F) {
+	for _, route := range []string{"/pkg.Service/Method", "/C/M", "/a..C/M", "/a.C/M/", "/a.C/M.x", "/a.b.C/M"} {
+		f.Add(route)
+	}
+	f.Fuzz(func(t *testing.T, route string) {
+		pkg, service, method, err := parseRoute(route)
+		if err != nil {
+			if pkg != "" || service != "" || method != "" {
+				t.Fatalf("parseRoute(%q) = (%q, %q, %q) along with %v", route, pkg, service, method, err)
+			}
+			return
+		}
+		if len(service) == 0 || len(method) == 0 || strings.ContainsAny(service, "./") || strings.Contains(method, "/") {
+			t.Fatalf("parseRoute(%q) = (%q, %q, %q)", route, pkg, service, method)
+		}
+		if len(pkg) > 0 && (strings.Contains(pkg, "/") || strings.HasPrefix(pkg, ".") || strings.HasSuffix(pkg, ".") || strings.Contains(pkg, "..")) {
+			t.Fatalf("parseRoute(%q) returned the invalid package %q", route, pkg)
+		}
+		for _, nested := range []bool{false, true} {
+			levels, err := routeLevels(nil, route, nested)
+			if err != nil {
+				t.Fatalf("routeLevels(%q, %v): %v", route, nested, err)
+			}
+			if got := levelsRoute(levels); got != route {
+				t.Fatalf("levelsRoute(routeLevels(%q, %v)) = %q", route, nested, got)
+			}
+			if packages := pathPackages(levels); packages != len(levels)-2 {
+				t.Fatalf("pathPackages(%q) = %d, want %d", levels, packages, len(levels)-2)
+			}
+		}
+	})
+}
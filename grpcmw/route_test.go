@@ -0,0 +1,119 @@
+package grpcmw
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		route   string
+		pkg     string
+		service string
+		method  string
+		err     error
+	}{
+		{route: "/pkg.Service/Method", pkg: "pkg", service: "Service", method: "Method"},
+		{route: "/a.b.c.Service/Method", pkg: "a.b.c", service: "Service", method: "Method"},
+		{route: "/C/M", service: "C", method: "M"},
+		{route: "/a.C/M.x", pkg: "a", service: "C", method: "M.x"},
+		{route: "/a..C/M", err: errInvalidRoute},
+		{route: "/a.C/M/", err: errInvalidRoute},
+		{route: "/.C/M", err: errInvalidRoute},
+		{route: "/a./M", err: errInvalidRoute},
+		{route: "/a.C/", err: errInvalidRoute},
+		{route: "/a.C//M", err: errInvalidRoute},
+		{route: "/a.C", err: errInvalidRoute},
+		{route: "a.C/M", err: errInvalidRoute},
+		{route: "", err: errInvalidRoute},
+	}
+	for _, test := range tests {
+		pkg, service, method, err := parseRoute(test.route)
+		if err != test.err || pkg != test.pkg || service != test.service || method != test.method {
+			t.Errorf("parseRoute(%q) = (%q, %q, %q, %v), want (%q, %q, %q, %v)",
+				test.route, pkg, service, method, err, test.pkg, test.service, test.method, test.err)
+		}
+	}
+}
+
+func TestRouteLevels(t *testing.T) {
+	tests := []struct {
+		route  string
+		nested bool
+		levels []string
+	}{
+		{route: "/C/M", levels: []string{"", "C", "M"}},
+		{route: "/C/M", nested: true, levels: []string{"", "C", "M"}},
+		{route: "/a.b.C/M.x", levels: []string{"a.b", "C", "M.x"}},
+		{route: "/a.b.C/M.x", nested: true, levels: []string{"a", "a.b", "C", "M.x"}},
+	}
+	for _, test := range tests {
+		levels, err := routeLevels(nil, test.route, test.nested)
+		if err != nil || !equalStrings(levels, test.levels) {
+			t.Errorf("routeLevels(%q, %v) = (%q, %v), want %q", test.route, test.nested, levels, err, test.levels)
+		}
+		if route := levelsRoute(levels); route != test.route {
+			t.Errorf("levelsRoute(%q) = %q, want %q", levels, route, test.route)
+		}
+	}
+}
+
+func TestRouteLevelsAllocs(t *testing.T) {
+	var buf [routeLevelsSize]string
+	for _, nested := range []bool{false, true} {
+		allocs := testing.AllocsPerRun(100, func() {
+			routeLevels(buf[:0], "/company.billing.v1.Service/Method", nested)
+		})
+		if allocs != 0 {
+			t.Errorf("routeLevels(nested: %v) allocates %v times, want 0", nested, allocs)
+		}
+	}
+}
+
+func TestRouteWithoutPackage(t *testing.T) {
+	var calls []string
+	r := NewServerRouter(WithUnknownRoutePolicy(UnknownRouteReject))
+	reg, created := r.RegisterPackage("")
+	if !created {
+		t.Fatal("RegisterPackage(\"\") did not create the level")
+	}
+	service := NewServerInterceptorRegister("C")
+	service.AddGRPCUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		calls = append(calls, info.FullMethod)
+		return handler(ctx, req)
+	})
+	reg.Register(service)
+
+	info, err := r.Resolve("/C/M")
+	if err != nil || !info.Known || !equalStrings(info.Levels, []string{"global", "", "C"}) {
+		t.Fatalf("Resolve(\"/C/M\") = (%+v, %v)", info, err)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	if _, err := r.UnaryResolver()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/C/M"}, handler); err != nil {
+		t.Fatalf("/C/M: %v", err)
+	}
+	if !equalStrings(calls, []string{"/C/M"}) {
+		t.Fatalf("calls = %q", calls)
+	}
+	_, err = r.UnaryResolver()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/D/M"}, handler)
+	if grpc.Code(err) != codes.Unimplemented {
+		t.Fatalf("/D/M: %v, want Unimplemented", err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,570 @@
+package grpcmw
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// side is what routers and introspection need to know about the levels of a
+// side, so that a single implementation is shared by server and client
+// routers. Levels are handled as `level` holding either a `ServerInterceptor`
+// or a `ClientInterceptor`, and compiled chains as `interface{}` holding an
+// interceptor of the corresponding type.
+type side struct {
+	// registerType is the name of the register interface of the side.
+	registerType string
+	unary        *chainKind
+	stream       *chainKind
+	// chains returns the chains of unary and stream interceptors of `lvl`.
+	chains func(lvl level) (unary, stream interface{})
+	// isRegister returns whether `lvl` is a register.
+	isRegister func(lvl level) bool
+	// get returns the sublevel registered at `key` in the register `reg`.
+	get func(reg level, key string) (level, bool)
+	// register registers `sub` in the register `reg`.
+	register func(reg, sub level) error
+	// newRegister returns a new register indexed by `index`.
+	newRegister func(index string) level
+	// walk calls `fn` for each sublevel of `reg`, a register that has not
+	// been created by this package (see `walkLevel`).
+	walk func(reg level, fn func(path []string, lvl level) error) error
+	// reject returns the interceptors rejecting the requests to unknown
+	// routes.
+	reject func() (unary, stream interface{})
+	// fallback returns the chains of interceptors of `m` for the requests to
+	// unknown routes.
+	fallback func(m Middleware) (unary, stream interface{})
+}
+
+// router is the implementation of routers shared by both sides. It publishes
+// a new immutable state each time its register is set or a route is compiled,
+// so that resolving a cached route never locks. Writers are serialized by
+// `lock`, which also guards `implicit`, the package levels registered as the
+// parents of another package and not requested on their own yet, indexed by
+// their package. `unaryWired` and `streamWired` are set atomically once the
+// corresponding resolver has been requested.
+type router struct {
+	side        *side
+	state       *atomic.Value // *routerState
+	lock        *sync.Mutex
+	options     routerOptions
+	unknown     *compiledRoute
+	implicit    map[string]level
+	unaryWired  int32
+	streamWired int32
+}
+
+// routerState holds the register of a router and the routes compiled from it
+// during `generation`. `epoch` is incremented each time the register is set.
+// Once `frozen`, the compiled routes never become stale.
+type routerState struct {
+	register   level
+	routes     map[string]*compiledRoute
+	generation uint64
+	epoch      uint64
+	frozen     bool
+	mode       FreezeMode
+	patterns   []*routerPattern
+}
+
+// routerPattern is a level that applies to every route matching `pattern`.
+type routerPattern struct {
+	pattern *routePattern
+	level   level
+}
+
+// compiledRoute holds the chains of interceptors compiled for a given route.
+// `known` is false for unknown routes (see `UnknownRoutePolicy`).
+type compiledRoute struct {
+	unary  interface{}
+	stream interface{}
+	known  bool
+}
+
+// newRouter returns a router of `s` with `register` as its global level.
+func newRouter(s *side, register level, options routerOptions) *router {
+	r := &router{
+		side:     s,
+		state:    &atomic.Value{},
+		lock:     &sync.Mutex{},
+		options:  options,
+		implicit: make(map[string]level),
+	}
+	r.unknown = r.newUnknownRoute()
+	r.state.Store(&routerState{
+		register: register,
+		routes:   make(map[string]*compiledRoute),
+	})
+	return r
+}
+
+// loadState returns the current state of the router. It must not be modified.
+func (r *router) loadState() *routerState {
+	return r.state.Load().(*routerState)
+}
+
+// newUnknownRoute returns the route used for the requests to unknown routes,
+// or nil if they go through the tree like any other route.
+func (r *router) newUnknownRoute() *compiledRoute {
+	route := &compiledRoute{}
+	switch r.options.unknownRoutes {
+	case UnknownRoutePassThrough:
+		route.unary, route.stream = r.side.unary.compile(nil), r.side.stream.compile(nil)
+	case UnknownRouteFallback:
+		unary, stream := r.side.fallback(r.options.fallback)
+		route.unary, route.stream = coreOf(unary).compile(), coreOf(stream).compile()
+	case UnknownRouteReject:
+		route.unary, route.stream = r.side.reject()
+	default:
+		return nil
+	}
+	return route
+}
+
+// walkPath calls `fn` for each level on the path of `pathTokens` from `lvl`,
+// starting with `lvl`, until a level is not found. The empty index of the
+// package level of the routes without package is looked up like any other.
+func (r *router) walkPath(pathTokens []string, lvl level, fn func(lvl level)) error {
+	fn(lvl)
+	if len(pathTokens) == 0 {
+		return nil
+	}
+	if !r.side.isRegister(lvl) {
+		return fmt.Errorf("Level %s does not implement %s", lvl.Index(), r.side.registerType)
+	}
+	sub, exists := r.side.get(lvl, pathTokens[0])
+	if !exists {
+		return nil
+	}
+	return r.walkPath(pathTokens[1:], sub, fn)
+}
+
+// levels returns the levels on the path of `route`, starting from the global
+// level, followed by the levels bound to the patterns matching `route`.
+// `paths` is the number of levels of the path. `known` is false if the path of
+// `route` does not reach a service level and no pattern matches `route`.
+func (r *router) levels(route string, state *routerState) (levels []level, paths int, known bool, err error) {
+	var buf [routeLevelsSize]string
+	pathTokens, err := routeLevels(buf[:0], route, r.options.nestedPackages)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	err = r.walkPath(pathTokens, state.register, func(lvl level) {
+		levels = append(levels, lvl)
+	})
+	if err != nil {
+		return nil, 0, false, err
+	}
+	paths = len(levels)
+	known = paths >= len(pathTokens)
+	for _, pattern := range state.patterns {
+		if pattern.pattern.match(route) {
+			levels = append(levels, pattern.level)
+			known = true
+		}
+	}
+	return levels, paths, known, nil
+}
+
+// compile flattens the interceptors of the levels of `route` into a single
+// chain for each type of interceptor. A chain referenced by several levels is
+// only called once, at its first position. Levels bound to a pattern matching
+// `route` come after the levels of the path, in the order they have been
+// added. Interceptors excluded by a level are skipped for the levels that come
+// before it, and for the levels bound to patterns if it is a level of the path
+// (see `levelExclusions`). Unknown routes are not compiled if the router has a
+// dedicated route for them.
+func (r *router) compile(route string, state *routerState) (*compiledRoute, error) {
+	levels, paths, known, err := r.levels(route, state)
+	if err != nil {
+		return nil, err
+	}
+	if !known && r.unknown != nil {
+		return &compiledRoute{}, nil
+	}
+	var (
+		unaries, streams []interface{}
+		seen             = make(map[*chain]struct{})
+	)
+	exclusions := levelExclusions(len(levels), paths, func(idx int) []string {
+		return levels[idx].Excluded()
+	})
+	for idx, lvl := range levels {
+		unary, stream := r.side.chains(lvl)
+		unaries = appendChain(unaries, r.side.unary, unary, seen, exclusions[idx])
+		streams = appendChain(streams, r.side.stream, stream, seen, exclusions[idx])
+	}
+	return &compiledRoute{
+		unary:  r.side.unary.compile(unaries),
+		stream: r.side.stream.compile(streams),
+		known:  known,
+	}, nil
+}
+
+// Resolve returns the description of the interceptors that are called for
+// `route` (e.g. "/pkg.Service/Method"), in the order they are called, without
+// calling any of them. The policy of the router for unknown routes is applied:
+// unless it is `UnknownRoutePrefix`, the interceptors described for an unknown
+// route are those called by the policy.
+func (r *router) Resolve(route string) (*RouteInfo, error) {
+	levels, paths, known, err := r.levels(route, r.loadState())
+	if (err != nil || !known) && r.unknown != nil {
+		return r.resolveUnknown(route), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		info = &RouteInfo{
+			Route:  route,
+			Known:  known,
+			Policy: r.options.unknownRoutes,
+			Levels: []string{},
+			Unary:  []ResolvedInterceptor{},
+			Stream: []ResolvedInterceptor{},
+		}
+		seen = make(map[*chain]struct{})
+	)
+	exclusions := levelExclusions(len(levels), paths, func(idx int) []string {
+		return levels[idx].Excluded()
+	})
+	for idx, lvl := range levels {
+		unary, stream := r.side.chains(lvl)
+		info.Levels = append(info.Levels, lvl.Index())
+		info.Unary = resolveChain(info.Unary, lvl.Index(), r.side.unary, unary, seen, exclusions[idx])
+		info.Stream = resolveChain(info.Stream, lvl.Index(), r.side.stream, stream, seen, exclusions[idx])
+	}
+	return info, nil
+}
+
+// resolveUnknown returns the description of the interceptors called by the
+// policy of the router for the unknown route `route`. It must only be called if
+// the policy is not `UnknownRoutePrefix`.
+func (r *router) resolveUnknown(route string) *RouteInfo {
+	info := &RouteInfo{
+		Route:  route,
+		Policy: r.options.unknownRoutes,
+		Levels: []string{},
+		Unary:  []ResolvedInterceptor{},
+		Stream: []ResolvedInterceptor{},
+	}
+	if r.options.unknownRoutes == UnknownRouteFallback {
+		unary, stream := r.side.fallback(r.options.fallback)
+		info.Unary = resolveChain(info.Unary, fallbackLevel, r.side.unary, unary, make(map[*chain]struct{}), nil)
+		info.Stream = resolveChain(info.Stream, fallbackLevel, r.side.stream, stream, make(map[*chain]struct{}), nil)
+	}
+	return info
+}
+
+// resolve returns the chains of interceptors to call for `method`, applying
+// the policy of the router for unknown routes.
+func (r *router) resolve(method string) (*compiledRoute, error) {
+	route, err := r.route(method)
+	if err == nil && route.known {
+		return route, nil
+	}
+	if r.options.onUnknownRoute != nil {
+		r.options.onUnknownRoute(method, err)
+	}
+	if r.unknown != nil {
+		return r.unknown, nil
+	}
+	return route, err
+}
+
+// route returns the compiled chains of interceptors for `method`. It only
+// compiles them if they are not cached yet or if the cache is stale. Only
+// known routes are cached, so that requests to arbitrary routes cannot grow the
+// cache: with `UnknownRoutePrefix`, the chains of unknown routes are compiled
+// for each request.
+func (r *router) route(method string) (*compiledRoute, error) {
+	generation := currentRoutesGeneration()
+	state := r.loadState()
+	if state.frozen || state.generation == generation {
+		if route, ok := state.routes[method]; ok {
+			return route, nil
+		}
+	}
+	route, err := r.compile(method, state)
+	if err != nil || !route.known {
+		return route, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	current := r.loadState()
+	if current.epoch != state.epoch || (!current.frozen && current.generation > generation) {
+		return route, nil
+	}
+	routes := make(map[string]*compiledRoute, len(current.routes)+1)
+	if current.frozen || current.generation == generation {
+		for key, cached := range current.routes {
+			routes[key] = cached
+		}
+	}
+	routes[method] = route
+	r.state.Store(&routerState{
+		register:   current.register,
+		routes:     routes,
+		generation: generation,
+		epoch:      current.epoch,
+		frozen:     current.frozen,
+		mode:       current.mode,
+		patterns:   current.patterns,
+	})
+	return route, nil
+}
+
+// swap sets `reg` as the register of the router along with the chains of
+// interceptors compiled for the routes it defines. The levels bound to patterns
+// are kept, unless it is a `reload`: they are replaced by `patterns`, and it
+// fails without setting `reg` if any of these routes cannot be compiled.
+func (r *router) swap(reg level, patterns []*routerPattern, reload bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	current := r.loadState()
+	if current.frozen {
+		return frozenError(current.mode)
+	}
+	state := &routerState{
+		register:   reg,
+		generation: currentRoutesGeneration(),
+		epoch:      current.epoch + 1,
+		patterns:   patterns,
+	}
+	if !reload {
+		state.patterns = current.patterns
+	}
+	routes, err := r.compileAll(state)
+	if err != nil && reload {
+		return err
+	}
+	state.routes = routes
+	r.state.Store(state)
+	r.implicit = make(map[string]level)
+	return nil
+}
+
+// reload sets up a new router with the options of `r` through `build`, then
+// sets its register and its patterns as those of `r`, only if the chains of
+// every route they define can be compiled.
+func (r *router) reload(build func(staging *router) error) error {
+	staging := newRouter(r.side, r.side.newRegister("global"), r.options)
+	if err := build(staging); err != nil {
+		return err
+	}
+	state := staging.loadState()
+	return r.swap(state.register, state.patterns, true)
+}
+
+// compileAll compiles the chains of interceptors of every route defined by the
+// register of `state`. Routes that cannot be compiled are skipped, and the
+// first error is returned along with the other routes. Package and service
+// levels that are not registers are reported as well, as the routes below them
+// could never be resolved.
+func (r *router) compileAll(state *routerState) (map[string]*compiledRoute, error) {
+	var (
+		routes = make(map[string]*compiledRoute)
+		first  error
+	)
+	walkLevel(r.side, []string{}, state.register, func(path []string, lvl level) error {
+		packages := pathPackages(path)
+		if len(path) < packages+2 {
+			if !r.side.isRegister(lvl) && len(path) > 0 && first == nil {
+				first = fmt.Errorf("Level %s does not implement %s", lvl.Index(), r.side.registerType)
+			}
+			return nil
+		}
+		if len(path) > packages+2 || (packages > 1 && !r.options.nestedPackages) {
+			return nil
+		}
+		route := levelsRoute(path)
+		compiled, err := r.compile(route, state)
+		switch {
+		case err != nil && first == nil:
+			first = fmt.Errorf("Route %s: %v", route, err)
+		case err == nil && compiled.known:
+			routes[route] = compiled
+		}
+		return nil
+	})
+	return routes, first
+}
+
+// Freeze freezes the interceptor register of the router (see
+// `ServerInterceptor.Freeze`) and compiles the chains of interceptors of every
+// method it defines. Routes that are not defined by the register are compiled
+// the first time they are requested and then cached as well. Setting the
+// register afterwards either fails with `ErrFrozen` or panics, depending on
+// `mode`. Levels bound to patterns are frozen as well. Chains referenced by the
+// levels of the router (e.g. merged registry interceptors) are not frozen, as
+// they may be shared with other routers: the router keeps on calling the
+// interceptors they had when it was frozen, while `Resolve` describes them as
+// they currently are.
+func (r *router) Freeze(mode FreezeMode) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	current := r.loadState()
+	if current.frozen {
+		return
+	}
+	current.register.Freeze(mode)
+	for _, pattern := range current.patterns {
+		pattern.level.Freeze(mode)
+	}
+	routes, _ := r.compileAll(current)
+	r.state.Store(&routerState{
+		register:   current.register,
+		routes:     routes,
+		generation: currentRoutesGeneration(),
+		epoch:      current.epoch,
+		frozen:     true,
+		mode:       mode,
+		patterns:   current.patterns,
+	})
+}
+
+// Frozen returns whether the router has been frozen.
+func (r *router) Frozen() bool {
+	return r.loadState().frozen
+}
+
+// addPattern adds `lvl` as a level that applies to every route matching
+// `pattern` (see `ServerRouter.AddPattern`).
+func (r *router) addPattern(pattern string, lvl level) error {
+	compiled, err := compileRoutePattern(pattern)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	current := r.loadState()
+	if current.frozen {
+		return frozenError(current.mode)
+	}
+	patterns := make([]*routerPattern, len(current.patterns), len(current.patterns)+1)
+	copy(patterns, current.patterns)
+	r.state.Store(&routerState{
+		register: current.register,
+		routes:   make(map[string]*compiledRoute),
+		epoch:    current.epoch + 1,
+		patterns: append(patterns, &routerPattern{pattern: compiled, level: lvl}),
+	})
+	return nil
+}
+
+// registerPackage returns the register of the level of the protobuf package
+// `pkg`, registering it along with its parent levels if needed (see
+// `ServerRouter.RegisterPackage`). A parent level that has been registered
+// along with a subpackage is still reported as created the first time its own
+// package is requested. If a level cannot be registered (e.g. because the
+// register of the router has been frozen in `FreezeError` mode), the failure is
+// reported with `grpclog` and a detached register is returned, which is never
+// reported as created.
+func (r *router) registerPackage(pkg string) (reg level, created bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	reg = r.loadState().register
+	levels := packageLevels(nil, pkg, r.options.nestedPackages)
+	for _, index := range levels {
+		sub, exists := r.side.get(reg, index)
+		if !exists {
+			sub = r.side.newRegister(index)
+			if err := r.side.register(reg, sub); err != nil {
+				grpclog.Warningf("Package %s not registered: %v", pkg, err)
+				return r.side.newRegister(levels[len(levels)-1]), false
+			}
+			r.implicit[index] = sub
+		}
+		if !r.side.isRegister(sub) {
+			panic(fmt.Errorf("Level %s does not implement %s", sub.Index(), r.side.registerType))
+		}
+		reg = sub
+	}
+	index := levels[len(levels)-1]
+	if implicit, ok := r.implicit[index]; ok {
+		created = implicit == reg
+		delete(r.implicit, index)
+	}
+	return
+}
+
+// levelsWithInterceptors returns the names of the levels of the router,
+// including the levels bound to patterns, that have unary and stream
+// interceptors.
+func (r *router) levelsWithInterceptors() (unaryLevels, streamLevels []string) {
+	check := func(name string, lvl level) {
+		unary, stream := r.side.chains(lvl)
+		if len(unary.(describer).Describe()) > 0 {
+			unaryLevels = append(unaryLevels, name)
+		}
+		if len(stream.(describer).Describe()) > 0 {
+			streamLevels = append(streamLevels, name)
+		}
+	}
+	state := r.loadState()
+	walkLevel(r.side, []string{}, state.register, func(path []string, lvl level) error {
+		check(levelName(path), lvl)
+		return nil
+	})
+	for _, pattern := range state.patterns {
+		check(pattern.pattern.pattern, pattern.level)
+	}
+	return
+}
+
+// walkLevel calls `fn` for `lvl` and then, if it is a register, recursively for
+// each of its sublevels sorted by index. It stops at the first error returned
+// by `fn` and returns it.
+func walkLevel(s *side, path []string, lvl level, fn func(path []string, lvl level) error) error {
+	if err := fn(path, lvl); err != nil {
+		return err
+	}
+	if reg, ok := lvl.(treeLevel); ok {
+		for _, sub := range reg.levelTree().sorted() {
+			if err := walkLevel(s, appendPath(path, sub.Index()), sub, fn); err != nil {
+				return err
+			}
+		}
+	} else if s.isRegister(lvl) {
+		return s.walk(lvl, func(subpath []string, sub level) error {
+			if len(subpath) == 0 {
+				return nil
+			}
+			return fn(appendPath(path, subpath...), sub)
+		})
+	}
+	return nil
+}
+
+// describeLevels returns the description of `lvl` and of all its sublevels.
+func describeLevels(s *side, lvl level) *LevelInfo {
+	var stack []*LevelInfo
+	walkLevel(s, []string{}, lvl, func(path []string, lvl level) error {
+		info := describeLevel(s, path, lvl)
+		if len(path) > 0 {
+			parent := stack[len(path)-1]
+			parent.Sublevels = append(parent.Sublevels, info)
+		}
+		stack = append(stack[:len(path)], info)
+		return nil
+	})
+	return stack[0]
+}
+
+// describeLevel returns the description of `lvl` without its sublevels.
+func describeLevel(s *side, path []string, lvl level) *LevelInfo {
+	unary, stream := s.chains(lvl)
+	return &LevelInfo{
+		Path:        path,
+		Index:       lvl.Index(),
+		Register:    s.isRegister(lvl),
+		UnaryCount:  countChain(s.unary, unary),
+		StreamCount: countChain(s.stream, stream),
+		Unary:       unary.(describer).Describe(),
+		Stream:      stream.(describer).Describe(),
+		Excluded:    lvl.Excluded(),
+	}
+}
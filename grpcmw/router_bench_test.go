@@ -0,0 +1,242 @@
+package grpcmw
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// baselineRouteRegexp is the regular expression the routers used to parse
+// routes with before they cached the chains of interceptors of each route.
+var baselineRouteRegexp = regexp.MustCompile(`\/(?:(.+)\.)?(.+)\/(.+)`)
+
+// baselineLevels calls `fn` for each level on the path of `route`, the way
+// routers did before they cached the chains of interceptors of each route.
+func baselineLevels(route string, lvl level, get func(reg level, key string) (level, bool), fn func(lvl level)) error {
+	matches := baselineRouteRegexp.FindStringSubmatch(route)
+	if len(matches) == 0 {
+		return errors.New("Invalid route")
+	}
+	fn(lvl)
+	for _, token := range matches[1:] {
+		if len(token) == 0 {
+			break
+		}
+		sub, exists := get(lvl, token)
+		if !exists {
+			break
+		}
+		fn(sub)
+		lvl = sub
+	}
+	return nil
+}
+
+// baselineServerResolvers returns the resolvers of `reg` that parse the route
+// and build its chain of interceptors for each request.
+func baselineServerResolvers(reg ServerInterceptorRegister) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		interceptor := NewUnaryServerInterceptor()
+		err := baselineLevels(info.FullMethod, reg, serverSide.get, func(lvl level) {
+			interceptor.AddInterceptor(lvl.(ServerInterceptor).UnaryServerInterceptor())
+		})
+		if err != nil {
+			return nil, err
+		}
+		return interceptor.Interceptor()(ctx, req, info, handler)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		interceptor := NewStreamServerInterceptor()
+		err := baselineLevels(info.FullMethod, reg, serverSide.get, func(lvl level) {
+			interceptor.AddInterceptor(lvl.(ServerInterceptor).StreamServerInterceptor())
+		})
+		if err != nil {
+			return err
+		}
+		return interceptor.Interceptor()(srv, ss, info, handler)
+	}
+	return unary, stream
+}
+
+// baselineClientResolvers is the client equivalent of
+// `baselineServerResolvers`.
+func baselineClientResolvers(reg ClientInterceptorRegister) (grpc.UnaryClientInterceptor, grpc.StreamClientInterceptor) {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		interceptor := NewUnaryClientInterceptor()
+		err := baselineLevels(method, reg, clientSide.get, func(lvl level) {
+			interceptor.AddInterceptor(lvl.(ClientInterceptor).UnaryClientInterceptor())
+		})
+		if err != nil {
+			return err
+		}
+		return interceptor.Interceptor()(ctx, method, req, reply, cc, invoker, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		interceptor := NewStreamClientInterceptor()
+		err := baselineLevels(method, reg, clientSide.get, func(lvl level) {
+			interceptor.AddInterceptor(lvl.(ClientInterceptor).StreamClientInterceptor())
+		})
+		if err != nil {
+			return nil, err
+		}
+		return interceptor.Interceptor()(ctx, desc, cc, method, streamer, opts...)
+	}
+	return unary, stream
+}
+
+const benchRoute = "/pkg.Service/Method"
+
+func benchServerRouter() ServerRouter {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ss)
+	}
+	r := NewServerRouter()
+	pkg, _ := r.RegisterPackage("pkg")
+	service := NewServerInterceptorRegister("Service")
+	method := NewServerInterceptor("Method")
+	pkg.Register(service)
+	service.Register(method)
+	for _, lvl := range []ServerInterceptor{r.GetRegister(), pkg, service, method} {
+		lvl.AddGRPCUnaryInterceptor(unary, unary).AddGRPCStreamInterceptor(stream, stream)
+	}
+	return r
+}
+
+func benchClientRouter() ClientRouter {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	r := NewClientRouter()
+	pkg, _ := r.RegisterPackage("pkg")
+	service := NewClientInterceptorRegister("Service")
+	method := NewClientInterceptor("Method")
+	pkg.Register(service)
+	service.Register(method)
+	for _, lvl := range []ClientInterceptor{r.GetRegister(), pkg, service, method} {
+		lvl.AddGRPCUnaryInterceptor(unary, unary).AddGRPCStreamInterceptor(stream, stream)
+	}
+	return r
+}
+
+// benchCalls runs `call` `b.N` times, from parallel goroutines if `parallel`
+// is true.
+func benchCalls(b *testing.B, parallel bool, call func()) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	if !parallel {
+		for i := 0; i < b.N; i++ {
+			call()
+		}
+		return
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			call()
+		}
+	})
+}
+
+func benchServerUnary(b *testing.B, resolver grpc.UnaryServerInterceptor, parallel bool) {
+	ctx, info := context.Background(), &grpc.UnaryServerInfo{FullMethod: benchRoute}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	benchCalls(b, parallel, func() {
+		resolver(ctx, nil, info, handler)
+	})
+}
+
+func benchServerStream(b *testing.B, resolver grpc.StreamServerInterceptor, parallel bool) {
+	info := &grpc.StreamServerInfo{FullMethod: benchRoute}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	}
+	benchCalls(b, parallel, func() {
+		resolver(nil, nil, info, handler)
+	})
+}
+
+func benchClientUnary(b *testing.B, resolver grpc.UnaryClientInterceptor, parallel bool) {
+	ctx := context.Background()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+	benchCalls(b, parallel, func() {
+		resolver(ctx, benchRoute, nil, nil, nil, invoker)
+	})
+}
+
+func benchClientStream(b *testing.B, resolver grpc.StreamClientInterceptor, parallel bool) {
+	ctx := context.Background()
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, nil
+	}
+	benchCalls(b, parallel, func() {
+		resolver(ctx, nil, nil, benchRoute, streamer)
+	})
+}
+
+func BenchmarkServerRouterUnary(b *testing.B) {
+	r := benchServerRouter()
+	unary, _ := baselineServerResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchServerUnary(b, unary, false) })
+	b.Run("cached", func(b *testing.B) { benchServerUnary(b, r.UnaryResolver(), false) })
+}
+
+func BenchmarkServerRouterStream(b *testing.B) {
+	r := benchServerRouter()
+	_, stream := baselineServerResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchServerStream(b, stream, false) })
+	b.Run("cached", func(b *testing.B) { benchServerStream(b, r.StreamResolver(), false) })
+}
+
+func BenchmarkClientRouterUnary(b *testing.B) {
+	r := benchClientRouter()
+	unary, _ := baselineClientResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchClientUnary(b, unary, false) })
+	b.Run("cached", func(b *testing.B) { benchClientUnary(b, r.UnaryResolver(), false) })
+}
+
+func BenchmarkClientRouterStream(b *testing.B) {
+	r := benchClientRouter()
+	_, stream := baselineClientResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchClientStream(b, stream, false) })
+	b.Run("cached", func(b *testing.B) { benchClientStream(b, r.StreamResolver(), false) })
+}
+
+func BenchmarkServerRouterUnaryParallel(b *testing.B) {
+	r := benchServerRouter()
+	unary, _ := baselineServerResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchServerUnary(b, unary, true) })
+	b.Run("cached", func(b *testing.B) { benchServerUnary(b, r.UnaryResolver(), true) })
+}
+
+func BenchmarkServerRouterStreamParallel(b *testing.B) {
+	r := benchServerRouter()
+	_, stream := baselineServerResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchServerStream(b, stream, true) })
+	b.Run("cached", func(b *testing.B) { benchServerStream(b, r.StreamResolver(), true) })
+}
+
+func BenchmarkClientRouterUnaryParallel(b *testing.B) {
+	r := benchClientRouter()
+	unary, _ := baselineClientResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchClientUnary(b, unary, true) })
+	b.Run("cached", func(b *testing.B) { benchClientUnary(b, r.UnaryResolver(), true) })
+}
+
+func BenchmarkClientRouterStreamParallel(b *testing.B) {
+	r := benchClientRouter()
+	_, stream := baselineClientResolvers(r.GetRegister())
+	b.Run("regexp", func(b *testing.B) { benchClientStream(b, stream, true) })
+	b.Run("cached", func(b *testing.B) { benchClientStream(b, r.StreamResolver(), true) })
+}
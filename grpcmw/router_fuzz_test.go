@@ -0,0 +1,158 @@
+//go:build go1.18
+// +build go1.18
+
+package grpcmw
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// fuzzRoutes are the seeds of the router fuzzers: known routes, routes only
+// matched by a pattern, unknown routes and routes that cannot be parsed.
+var fuzzRoutes = []string{
+	"/pkg.Service/Method",
+	"/pkg.Service/ListItems",
+	"/pkg.Other/Method",
+	"/company.billing.v1.Invoices/Get",
+	"/Health/Check",
+	"/other.Service/ListItems",
+	"/pkg.Service/Method/",
+	"/pkg..Service/Method",
+	"//Method",
+	"",
+}
+
+// fuzzServerRouter returns a server router with `policy` whose unary
+// interceptors record their name in `calls`.
+func fuzzServerRouter(calls *[]string, policy UnknownRoutePolicy, nested bool) ServerRouter {
+	opts := []RouterOption{WithUnknownRoutePolicy(policy)}
+	if nested {
+		opts = append(opts, WithNestedPackages())
+	}
+	r := NewServerRouter(opts...)
+	r.GetRegister().AddNamedGRPCUnaryInterceptor("global", recordServerUnary(calls, "global"))
+	for _, pkg := range []string{"pkg", "company.billing.v1", ""} {
+		reg, _ := r.RegisterPackage(pkg)
+		reg.AddNamedGRPCUnaryInterceptor("package "+pkg, recordServerUnary(calls, "package "+pkg))
+		service := NewServerInterceptorRegister("Service")
+		service.AddNamedGRPCUnaryInterceptor("service", recordServerUnary(calls, "service"))
+		reg.Register(service)
+	}
+	health, _ := r.RegisterPackage("")
+	health.Register(NewServerInterceptorRegister("Health"))
+	listing := NewServerInterceptor("listing")
+	listing.AddNamedGRPCUnaryInterceptor("listing", recordServerUnary(calls, "listing"))
+	r.AddPattern("/*/List*", listing)
+	return r
+}
+
+func FuzzServerRouter(f *testing.F) {
+	for _, route := range fuzzRoutes {
+		f.Add(route, false)
+		f.Add(route, true)
+	}
+	f.Fuzz(func(t *testing.T, route string, nested bool) {
+		var calls []string
+		for _, policy := range []UnknownRoutePolicy{UnknownRoutePrefix, UnknownRoutePassThrough, UnknownRouteReject} {
+			r := fuzzServerRouter(&calls, policy, nested)
+			info, resolveErr := r.Resolve(route)
+			calls = nil
+			_, err := r.UnaryResolver()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: route}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			if (resolveErr != nil) != (err != nil) && policy != UnknownRouteReject {
+				t.Fatalf("%v: Resolve(%q) failed with %v but the resolver with %v", policy, route, resolveErr, err)
+			}
+			if resolveErr != nil {
+				continue
+			}
+			if policy == UnknownRouteReject && !info.Known {
+				if err == nil {
+					t.Fatalf("%v: the unknown route %q has not been rejected", policy, route)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%v: resolver(%q): %v", policy, route, err)
+			}
+			if want := resolvedNames(info.Unary); !equalStrings(calls, want) {
+				t.Fatalf("%v: resolver(%q) called %q, Resolve reported %q", policy, route, calls, want)
+			}
+			_, cached := r.(*serverRouter).loadState().routes[route]
+			if cached != info.Known {
+				t.Fatalf("%v: route %q cached: %v, known: %v", policy, route, cached, info.Known)
+			}
+		}
+	})
+}
+
+// fuzzClientRouter returns a client router with `policy` whose unary
+// interceptors record their name in `calls`.
+func fuzzClientRouter(calls *[]string, policy UnknownRoutePolicy, nested bool) ClientRouter {
+	record := func(name string) grpc.UnaryClientInterceptor {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			*calls = append(*calls, name)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+	opts := []RouterOption{WithUnknownRoutePolicy(policy)}
+	if nested {
+		opts = append(opts, WithNestedPackages())
+	}
+	r := NewClientRouter(opts...)
+	r.GetRegister().AddNamedGRPCUnaryInterceptor("global", record("global"))
+	for _, pkg := range []string{"pkg", "company.billing.v1", ""} {
+		reg, _ := r.RegisterPackage(pkg)
+		reg.AddNamedGRPCUnaryInterceptor("package "+pkg, record("package "+pkg))
+		service := NewClientInterceptorRegister("Service")
+		service.AddNamedGRPCUnaryInterceptor("service", record("service"))
+		reg.Register(service)
+	}
+	listing := NewClientInterceptor("listing")
+	listing.AddNamedGRPCUnaryInterceptor("listing", record("listing"))
+	r.AddPattern("/*/List*", listing)
+	return r
+}
+
+func FuzzClientRouter(f *testing.F) {
+	for _, route := range fuzzRoutes {
+		f.Add(route, false)
+		f.Add(route, true)
+	}
+	f.Fuzz(func(t *testing.T, route string, nested bool) {
+		var calls []string
+		for _, policy := range []UnknownRoutePolicy{UnknownRoutePrefix, UnknownRoutePassThrough, UnknownRouteReject} {
+			r := fuzzClientRouter(&calls, policy, nested)
+			info, resolveErr := r.Resolve(route)
+			calls = nil
+			err := r.UnaryResolver()(context.Background(), route, nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return nil
+			})
+			if (resolveErr != nil) != (err != nil) && policy != UnknownRouteReject {
+				t.Fatalf("%v: Resolve(%q) failed with %v but the resolver with %v", policy, route, resolveErr, err)
+			}
+			if resolveErr != nil {
+				continue
+			}
+			if policy == UnknownRouteReject && !info.Known {
+				if err == nil {
+					t.Fatalf("%v: the unknown route %q has not been rejected", policy, route)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%v: resolver(%q): %v", policy, route, err)
+			}
+			if want := resolvedNames(info.Unary); !equalStrings(calls, want) {
+				t.Fatalf("%v: resolver(%q) called %q, Resolve reported %q", policy, route, calls, want)
+			}
+			_, cached := r.(*clientRouter).loadState().routes[route]
+			if cached != info.Known {
+				t.Fatalf("%v: route %q cached: %v, known: %v", policy, route, cached, info.Known)
+			}
+		}
+	})
+}
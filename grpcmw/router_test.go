@@ -0,0 +1,430 @@
+package grpcmw
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// recordServerUnary returns a unary interceptor that appends `name` to `calls`.
+func recordServerUnary(calls *[]string, name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	}
+}
+
+// callServerUnary calls `resolver` for `route` and returns the names recorded
+// in `calls` by the interceptors it called.
+func callServerUnary(t *testing.T, resolver grpc.UnaryServerInterceptor, calls *[]string, route string) []string {
+	*calls = nil
+	_, err := resolver(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: route}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("%s: %v", route, err)
+	}
+	return *calls
+}
+
+// resolvedNames returns the names of `interceptors`.
+func resolvedNames(interceptors []ResolvedInterceptor) []string {
+	var names []string
+	for _, i := range interceptors {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+func TestServerRouterCacheInvalidation(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	resolver := r.UnaryResolver()
+	pkg, _ := r.RegisterPackage("pkg")
+	service := NewServerInterceptorRegister("Service")
+	pkg.Register(service)
+
+	steps := []struct {
+		name   string
+		modify func()
+		want   []string
+	}{
+		{
+			name:   "AddGRPCUnaryInterceptor",
+			modify: func() { service.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "service")) },
+			want:   []string{"service"},
+		},
+		{
+			name: "AddUnaryInterceptor",
+			modify: func() {
+				r.GetRegister().AddUnaryInterceptor(NewUnaryServerInterceptor(recordServerUnary(&calls, "global")))
+			},
+			want: []string{"global", "service"},
+		},
+		{
+			name: "Merge",
+			modify: func() {
+				merged := NewServerInterceptor("merged")
+				merged.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "merged"))
+				pkg.Merge(merged)
+			},
+			want: []string{"global", "merged", "service"},
+		},
+		{
+			name: "Register",
+			modify: func() {
+				method := NewServerInterceptor("Method")
+				method.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "method"))
+				service.Register(method)
+			},
+			want: []string{"global", "merged", "service", "method"},
+		},
+		{
+			name:   "RemoveInterceptor",
+			modify: func() { pkg.RemoveInterceptor("merged") },
+			want:   []string{"global", "service", "method"},
+		},
+	}
+	callServerUnary(t, resolver, &calls, "/pkg.Service/Method")
+	for _, step := range steps {
+		step.modify()
+		if got := callServerUnary(t, resolver, &calls, "/pkg.Service/Method"); !equalStrings(got, step.want) {
+			t.Fatalf("after %s: called %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func TestServerRouterBaselineOrder(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	shared := NewServerInterceptor("shared")
+	shared.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "shared"))
+	pkg, _ := r.RegisterPackage("pkg")
+	service := NewServerInterceptorRegister("Service")
+	method := NewServerInterceptor("Method")
+	pkg.Register(service)
+	service.Register(method)
+	r.GetRegister().AddGRPCUnaryInterceptor(recordServerUnary(&calls, "global1"), recordServerUnary(&calls, "global2"))
+	pkg.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "pkg")).Merge(shared)
+	service.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "service"))
+	method.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "method1"), recordServerUnary(&calls, "method2"))
+
+	baseline, _ := baselineServerResolvers(r.GetRegister())
+	for _, route := range []string{"/pkg.Service/Method", "/pkg.Service/Other", "/pkg.Other/Method", "/other.Service/Method"} {
+		want := append([]string(nil), callServerUnary(t, baseline, &calls, route)...)
+		if got := callServerUnary(t, r.UnaryResolver(), &calls, route); !equalStrings(got, want) {
+			t.Errorf("%s: called %q, want %q", route, got, want)
+		}
+	}
+}
+
+func TestServerRouterCachesKnownRoutesOnly(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.Register(NewServerInterceptorRegister("Service"))
+	resolver := r.UnaryResolver()
+	for _, route := range []string{"/pkg.Service/Method", "/pkg.Unknown/Method", "/other.Service/Method"} {
+		callServerUnary(t, resolver, &calls, route)
+	}
+	routes := r.(*serverRouter).loadState().routes
+	if _, ok := routes["/pkg.Service/Method"]; len(routes) != 1 || !ok {
+		t.Fatalf("cached routes: %v", routes)
+	}
+}
+
+func TestServerRouterResolverAllocs(t *testing.T) {
+	r := NewServerRouter()
+	r.GetRegister().AddGRPCUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	})
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.Register(NewServerInterceptorRegister("Service"))
+	if err := r.AddPattern("/*/List*", NewServerInterceptor("listing")); err != nil {
+		t.Fatalf("AddPattern: %v", err)
+	}
+	resolver := r.UnaryResolver()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	for _, route := range []string{"/pkg.Service/Method", "/other.Service/ListItems"} {
+		info := &grpc.UnaryServerInfo{FullMethod: route}
+		allocs := testing.AllocsPerRun(100, func() {
+			resolver(context.Background(), nil, info, handler)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: the resolver allocates %v times, want 0 once the route is cached", route, allocs)
+		}
+	}
+}
+
+// TestServerRouterConcurrentModifications is meant to be run with `-race`: it
+// adds and removes interceptors and levels while requests are resolved.
+func TestServerRouterConcurrentModifications(t *testing.T) {
+	r := NewServerRouter()
+	pkg, _ := r.RegisterPackage("pkg")
+	service := NewServerInterceptorRegister("Service")
+	pkg.Register(service)
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ss)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unaryResolver, streamResolver := r.UnaryResolver(), r.StreamResolver()
+			unaryInfo := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+			streamInfo := &grpc.StreamServerInfo{FullMethod: "/pkg.Service/Method"}
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				ctx := context.Background()
+				if _, err := unaryResolver(ctx, nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, nil
+				}); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := streamResolver(nil, nil, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+					return nil
+				}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("interceptor%d", i)
+		method := NewServerInterceptor("Method")
+		method.AddNamedGRPCUnaryInterceptor(name, unary)
+		service.Register(method)
+		pkg.AddNamedGRPCUnaryInterceptor(name, unary).AddNamedGRPCStreamInterceptor(name, stream)
+		r.GetRegister().Merge(NewServerInterceptor(name))
+		if err := pkg.RemoveInterceptor(name); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.Unregister("Method"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestResolveUnknownRoutePolicy(t *testing.T) {
+	var calls []string
+	fallback := Middleware{Name: "fallback", UnaryServer: recordServerUnary(&calls, "fallback")}
+	tests := []struct {
+		opt    RouterOption
+		policy UnknownRoutePolicy
+		unary  []string
+	}{
+		{opt: WithUnknownRoutePolicy(UnknownRoutePrefix), policy: UnknownRoutePrefix, unary: []string{"global"}},
+		{opt: WithUnknownRoutePolicy(UnknownRoutePassThrough), policy: UnknownRoutePassThrough},
+		{opt: WithUnknownRoutePolicy(UnknownRouteReject), policy: UnknownRouteReject},
+		{opt: WithFallback(fallback), policy: UnknownRouteFallback, unary: []string{"fallback"}},
+	}
+	for _, test := range tests {
+		r := NewServerRouter(test.opt)
+		r.GetRegister().AddNamedGRPCUnaryInterceptor("global", recordServerUnary(&calls, "global"))
+		info, err := r.Resolve("/pkg.Service/Method")
+		if err != nil {
+			t.Fatalf("%v: %v", test.policy, err)
+		}
+		var unary []string
+		for _, i := range info.Unary {
+			unary = append(unary, i.Name)
+		}
+		if info.Known || info.Policy != test.policy || !equalStrings(unary, test.unary) {
+			t.Errorf("%v: Resolve = %+v, want the unary interceptors %q", test.policy, info, test.unary)
+		}
+	}
+}
+
+func TestServerRouterPatternRoutesAreKnown(t *testing.T) {
+	var calls []string
+	fallback := Middleware{Name: "fallback", UnaryServer: recordServerUnary(&calls, "fallback")}
+	for _, opt := range []RouterOption{
+		WithUnknownRoutePolicy(UnknownRoutePrefix),
+		WithUnknownRoutePolicy(UnknownRoutePassThrough),
+		WithUnknownRoutePolicy(UnknownRouteReject),
+		WithFallback(fallback),
+	} {
+		r := NewServerRouter(opt)
+		listing := NewServerInterceptor("listing")
+		listing.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "listing"))
+		if err := r.AddPattern("/*/List*", listing); err != nil {
+			t.Fatalf("AddPattern: %v", err)
+		}
+		policy := r.(*serverRouter).options.unknownRoutes
+		info, err := r.Resolve("/pkg.Service/ListItems")
+		if err != nil || !info.Known {
+			t.Errorf("%v: Resolve = %+v, %v, want a known route", policy, info, err)
+		}
+		resolver := r.UnaryResolver()
+		if got := callServerUnary(t, resolver, &calls, "/pkg.Service/ListItems"); !equalStrings(got, []string{"listing"}) {
+			t.Errorf("%v: called %q, want the pattern level", policy, got)
+		}
+		if _, ok := r.(*serverRouter).loadState().routes["/pkg.Service/ListItems"]; !ok {
+			t.Errorf("%v: the route matching a pattern has not been cached", policy)
+		}
+	}
+}
+
+func TestServerRouterReloadPatterns(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	old := NewServerInterceptor("old")
+	old.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "old"))
+	if err := r.AddPattern("/*/*", old); err != nil {
+		t.Fatal(err)
+	}
+	err := r.Reload(func(staging ServerRouter) error {
+		pkg, _ := staging.RegisterPackage("pkg")
+		pkg.Register(NewServerInterceptorRegister("Service"))
+		lvl := NewServerInterceptor("new")
+		lvl.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "new"))
+		return staging.AddPattern("/pkg.*/*", lvl)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callServerUnary(t, r.UnaryResolver(), &calls, "/pkg.Service/Method"); !equalStrings(got, []string{"new"}) {
+		t.Fatalf("called %q, want %q", got, []string{"new"})
+	}
+}
+
+func TestServerRouterRegisterPackageFrozen(t *testing.T) {
+	r := NewServerRouter()
+	r.Freeze(FreezeError)
+	if reg, created := r.RegisterPackage("late"); reg == nil || created {
+		t.Errorf("RegisterPackage = %v, %v, want a detached register that has not been created", reg, created)
+	}
+	if _, exists := r.GetRegister().(ServerInterceptorRegister).Get("late"); exists {
+		t.Error("RegisterPackage registered a package on a frozen router")
+	}
+
+	r = NewServerRouter()
+	r.Freeze(FreezePanic)
+	defer func() {
+		if err := recover(); err != ErrFrozen {
+			t.Errorf("RegisterPackage panicked with %v, want ErrFrozen", err)
+		}
+	}()
+	r.RegisterPackage("late")
+}
+
+func TestServerRouterExclude(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	r.GetRegister().
+		AddNamedGRPCUnaryInterceptor("auth", recordServerUnary(&calls, "auth")).
+		AddNamedGRPCUnaryInterceptor("log", recordServerUnary(&calls, "log"))
+	shared := NewServerInterceptor("shared")
+	shared.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "shared"))
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.Merge(shared)
+	service := NewServerInterceptorRegister("Service")
+	service.Register(NewServerInterceptor("Method"))
+	service.Register(NewServerInterceptor("Check").Exclude("auth"))
+	pkg.Register(service)
+	listing := NewServerInterceptor("listing")
+	listing.AddNamedGRPCUnaryInterceptor("paginate", recordServerUnary(&calls, "paginate"))
+	r.AddPattern("/*/List*", listing)
+	other := NewServerInterceptorRegister("Other")
+	other.Exclude("shared", "paginate")
+	other.Register(NewServerInterceptor("ListItems"))
+	pkg.Register(other)
+
+	for route, want := range map[string][]string{
+		"/pkg.Service/Method":    {"auth", "log", "shared"},
+		"/pkg.Service/Check":     {"log", "shared"},
+		"/pkg.Service/ListItems": {"auth", "log", "shared", "paginate"},
+		"/pkg.Other/ListItems":   {"auth", "log"},
+		"/pkg.Other/Method":      {"auth", "log"},
+	} {
+		if got := callServerUnary(t, r.UnaryResolver(), &calls, route); !equalStrings(got, want) {
+			t.Errorf("%s: called %q, want %q", route, got, want)
+		}
+	}
+}
+
+func TestServerRouterNamedInterceptors(t *testing.T) {
+	var calls []string
+	r := NewServerRouter()
+	resolver := r.UnaryResolver()
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.Register(NewServerInterceptorRegister("Service"))
+	global := r.GetRegister()
+	global.
+		AddNamedGRPCUnaryInterceptor("auth", recordServerUnary(&calls, "auth")).
+		AddNamedGRPCUnaryInterceptor("log", recordServerUnary(&calls, "log"))
+
+	steps := []struct {
+		name   string
+		modify func() error
+		want   []string
+	}{
+		{
+			name: "InsertBefore",
+			modify: func() error {
+				return global.UnaryServerInterceptor().InsertBefore("log", recordServerUnary(&calls, "validation"))
+			},
+			want: []string{"auth", "validation", "log"},
+		},
+		{
+			name: "InsertAfter",
+			modify: func() error {
+				return global.UnaryServerInterceptor().InsertAfter("auth", recordServerUnary(&calls, "tenant"))
+			},
+			want: []string{"auth", "tenant", "validation", "log"},
+		},
+		{
+			name: "ReplaceGRPCUnaryInterceptor",
+			modify: func() error {
+				return global.ReplaceGRPCUnaryInterceptor("auth", recordServerUnary(&calls, "fake auth"))
+			},
+			want: []string{"fake auth", "tenant", "validation", "log"},
+		},
+		{
+			name:   "RemoveInterceptor",
+			modify: func() error { return global.RemoveInterceptor("log") },
+			want:   []string{"fake auth", "tenant", "validation"},
+		},
+	}
+	callServerUnary(t, resolver, &calls, "/pkg.Service/Method")
+	for _, step := range steps {
+		if err := step.modify(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := callServerUnary(t, resolver, &calls, "/pkg.Service/Method"); !equalStrings(got, step.want) {
+			t.Fatalf("after %s: called %q, want %q", step.name, got, step.want)
+		}
+	}
+
+	if err := global.RemoveInterceptor("log"); err != ErrInterceptorNotFound {
+		t.Errorf("RemoveInterceptor of a removed interceptor = %v, want ErrInterceptorNotFound", err)
+	}
+	if err := global.UnaryServerInterceptor().InsertBefore("log", recordServerUnary(&calls, "lost")); err != ErrInterceptorNotFound {
+		t.Errorf("InsertBefore a removed interceptor = %v, want ErrInterceptorNotFound", err)
+	}
+	if err := global.ReplaceGRPCUnaryInterceptor("log", recordServerUnary(&calls, "lost")); err != ErrInterceptorNotFound {
+		t.Errorf("ReplaceGRPCUnaryInterceptor of a removed interceptor = %v, want ErrInterceptorNotFound", err)
+	}
+}
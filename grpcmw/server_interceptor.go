@@ -1,8 +1,6 @@
 package grpcmw
 
 import (
-	"sync"
-
 	"golang.org/x/net/context"
 
 	"google.golang.org/grpc"
@@ -17,9 +15,46 @@ type StreamServerInterceptor interface {
 	Interceptor() grpc.StreamServerInterceptor
 	// AddGRPCInterceptor adds given interceptors to the chain.
 	AddGRPCInterceptor(i ...grpc.StreamServerInterceptor) StreamServerInterceptor
-	// AddInterceptor is a convenient way for adding `StreamServerInterceptor`
-	// to the chain of interceptors.
+	// AddGRPCInterceptorWithPriority adds given interceptors to the chain with
+	// `priority` as their priority.
+	AddGRPCInterceptorWithPriority(priority Priority, i ...grpc.StreamServerInterceptor) StreamServerInterceptor
+	// AddInterceptor adds given chains of interceptors to the chain. They are
+	// kept by reference, so interceptors added to them later on are also
+	// called.
 	AddInterceptor(i ...StreamServerInterceptor) StreamServerInterceptor
+	// AddNamed adds `i` to the chain under `name` so that it can later be
+	// removed, replaced or used as a position for insertions.
+	AddNamed(name string, i grpc.StreamServerInterceptor) StreamServerInterceptor
+	// AddNamedWithPriority is the same as `AddNamed` with `priority` as the
+	// priority of `i`.
+	AddNamedWithPriority(name string, priority Priority, i grpc.StreamServerInterceptor) StreamServerInterceptor
+	// AddNamedInterceptor adds the chain of interceptors `i` to the chain under
+	// `name`. It is kept by reference like with `AddInterceptor`.
+	AddNamedInterceptor(name string, i StreamServerInterceptor) StreamServerInterceptor
+	// AddMiddleware adds the `grpc.StreamServerInterceptor` of each given middleware
+	// to the chain. Middlewares that do not define any are ignored.
+	AddMiddleware(m ...Middleware) StreamServerInterceptor
+	// Remove removes the interceptor named `name` from the chain.
+	Remove(name string) error
+	// Replace replaces the interceptor named `name` with `i`.
+	Replace(name string, i grpc.StreamServerInterceptor) error
+	// InsertBefore inserts given interceptors right before the interceptor
+	// named `name`.
+	InsertBefore(name string, i ...grpc.StreamServerInterceptor) error
+	// InsertAfter inserts given interceptors right after the interceptor named
+	// `name`.
+	InsertAfter(name string, i ...grpc.StreamServerInterceptor) error
+	// Describe returns the description of the elements of the chain sorted by
+	// priority, the chains it references being described and sorted on their
+	// own. Since priorities apply across the referenced chains, this is not
+	// necessarily the order in which the interceptors are called (see
+	// `ServerRouter.Resolve`).
+	Describe() []InterceptorInfo
+	// Freeze makes the chain immutable. `mode` defines how later
+	// modifications are handled. The chains it references are not frozen.
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the chain has been frozen.
+	Frozen() bool
 }
 
 // UnaryServerInterceptor represents a server interceptor for gRPC methods that
@@ -31,34 +66,109 @@ type UnaryServerInterceptor interface {
 	Interceptor() grpc.UnaryServerInterceptor
 	// AddGRPCInterceptor adds given interceptors to the chain.
 	AddGRPCInterceptor(i ...grpc.UnaryServerInterceptor) UnaryServerInterceptor
-	// AddInterceptor is a convenient way for adding `UnaryServerInterceptor`
-	// to the chain of interceptors.
+	// AddGRPCInterceptorWithPriority adds given interceptors to the chain with
+	// `priority` as their priority.
+	AddGRPCInterceptorWithPriority(priority Priority, i ...grpc.UnaryServerInterceptor) UnaryServerInterceptor
+	// AddInterceptor adds given chains of interceptors to the chain. They are
+	// kept by reference, so interceptors added to them later on are also
+	// called.
 	AddInterceptor(i ...UnaryServerInterceptor) UnaryServerInterceptor
+	// AddNamed adds `i` to the chain under `name` so that it can later be
+	// removed, replaced or used as a position for insertions.
+	AddNamed(name string, i grpc.UnaryServerInterceptor) UnaryServerInterceptor
+	// AddNamedWithPriority is the same as `AddNamed` with `priority` as the
+	// priority of `i`.
+	AddNamedWithPriority(name string, priority Priority, i grpc.UnaryServerInterceptor) UnaryServerInterceptor
+	// AddNamedInterceptor adds the chain of interceptors `i` to the chain under
+	// `name`. It is kept by reference like with `AddInterceptor`.
+	AddNamedInterceptor(name string, i UnaryServerInterceptor) UnaryServerInterceptor
+	// AddMiddleware adds the `grpc.UnaryServerInterceptor` of each given middleware
+	// to the chain. Middlewares that do not define any are ignored.
+	AddMiddleware(m ...Middleware) UnaryServerInterceptor
+	// Remove removes the interceptor named `name` from the chain.
+	Remove(name string) error
+	// Replace replaces the interceptor named `name` with `i`.
+	Replace(name string, i grpc.UnaryServerInterceptor) error
+	// InsertBefore inserts given interceptors right before the interceptor
+	// named `name`.
+	InsertBefore(name string, i ...grpc.UnaryServerInterceptor) error
+	// InsertAfter inserts given interceptors right after the interceptor named
+	// `name`.
+	InsertAfter(name string, i ...grpc.UnaryServerInterceptor) error
+	// Describe returns the description of the elements of the chain sorted by
+	// priority, the chains it references being described and sorted on their
+	// own. Since priorities apply across the referenced chains, this is not
+	// necessarily the order in which the interceptors are called (see
+	// `ServerRouter.Resolve`).
+	Describe() []InterceptorInfo
+	// Freeze makes the chain immutable. `mode` defines how later
+	// modifications are handled. The chains it references are not frozen.
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the chain has been frozen.
+	Frozen() bool
 }
 
+// streamServerInterceptor is a chain of `grpc.StreamServerInterceptor`.
 type streamServerInterceptor struct {
-	interceptors []grpc.StreamServerInterceptor
-	lock         *sync.RWMutex
+	*chain
 }
 
-type unaryServerInterceptor struct {
-	interceptors []grpc.UnaryServerInterceptor
-	lock         *sync.RWMutex
+// streamServerKind describes the chains of `grpc.StreamServerInterceptor`.
+var streamServerKind = &chainKind{
+	compile: func(arr []interface{}) interface{} {
+		interceptors := make([]grpc.StreamServerInterceptor, len(arr))
+		for idx, i := range arr {
+			interceptors[idx] = i.(grpc.StreamServerInterceptor)
+		}
+		return compileStreamServerInterceptors(interceptors)
+	},
+	interceptor: func(child interface{}) interface{} {
+		return child.(StreamServerInterceptor).Interceptor()
+	},
 }
 
 // NewStreamServerInterceptor returns a new `StreamServerInterceptor`.
 // It initializes its interceptor chain with `arr`.
 // This implementation is thread-safe.
+//
+// Interceptors are called by ascending priority and, for a given priority, in
+// the order they have been added.
 func NewStreamServerInterceptor(arr ...grpc.StreamServerInterceptor) StreamServerInterceptor {
-	return &streamServerInterceptor{
-		interceptors: arr,
-		lock:         &sync.RWMutex{},
+	return &streamServerInterceptor{chain: newChain(streamServerKind, streamServerInterceptors(arr))}
+}
+
+// streamServerInterceptors converts `arr` for the methods of `chain`.
+func streamServerInterceptors(arr []grpc.StreamServerInterceptor) []interface{} {
+	ret := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		ret[idx] = i
 	}
+	return ret
 }
 
-func chainStreamServerInterceptor(current grpc.StreamServerInterceptor, info *grpc.StreamServerInfo, next grpc.StreamHandler) grpc.StreamHandler {
+func chainStreamServerInterceptor(arr []grpc.StreamServerInterceptor, idx int, info *grpc.StreamServerInfo, handler grpc.StreamHandler) grpc.StreamHandler {
+	if idx == len(arr) {
+		return handler
+	}
 	return func(srv interface{}, stream grpc.ServerStream) error {
-		return current(srv, stream, info, next)
+		return arr[idx](srv, stream, info, chainStreamServerInterceptor(arr, idx+1, info, handler))
+	}
+}
+
+// compileStreamServerInterceptors chains `arr` into a single
+// `grpc.StreamServerInterceptor`. The handler of each element is only built
+// when the previous element calls it.
+func compileStreamServerInterceptors(arr []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	switch len(arr) {
+	case 0:
+		return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, ss)
+		}
+	case 1:
+		return arr[0]
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return arr[0](srv, ss, info, chainStreamServerInterceptor(arr, 1, info, handler))
 	}
 }
 
@@ -67,52 +177,160 @@ func chainStreamServerInterceptor(current grpc.StreamServerInterceptor, info *gr
 //
 // The `handler` passed to each interceptor is either the next interceptor or,
 // for the last element of the chain, the target method.
-func (si streamServerInterceptor) Interceptor() grpc.StreamServerInterceptor {
+//
+// The chain is compiled once and compiled again only when it or any of the
+// chains it references is modified.
+func (c *streamServerInterceptor) Interceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		// TODO: Find a more efficient way
-		interceptor := handler
-		si.lock.RLock()
-		for idx := len(si.interceptors) - 1; idx >= 0; idx-- {
-			interceptor = chainStreamServerInterceptor(si.interceptors[idx], info, interceptor)
-		}
-		si.lock.RUnlock()
-		return interceptor(srv, ss)
+		return c.compile().(grpc.StreamServerInterceptor)(srv, ss, info, handler)
 	}
 }
 
-// AddGRPCInterceptor adds `arr` to the chain of interceptors.
-func (si *streamServerInterceptor) AddGRPCInterceptor(arr ...grpc.StreamServerInterceptor) StreamServerInterceptor {
-	si.lock.Lock()
-	defer si.lock.Unlock()
-	si.interceptors = append(si.interceptors, arr...)
-	return si
+// AddGRPCInterceptor adds `arr` to the chain of interceptors with
+// `PhaseDefault` as their priority.
+func (c *streamServerInterceptor) AddGRPCInterceptor(arr ...grpc.StreamServerInterceptor) StreamServerInterceptor {
+	return c.AddGRPCInterceptorWithPriority(PhaseDefault, arr...)
+}
+
+// AddGRPCInterceptorWithPriority adds `arr` to the chain of interceptors with
+// `priority` as their priority.
+func (c *streamServerInterceptor) AddGRPCInterceptorWithPriority(priority Priority, arr ...grpc.StreamServerInterceptor) StreamServerInterceptor {
+	c.add(priority, streamServerInterceptors(arr))
+	return c
 }
 
-// AddInterceptor is a convenient way for adding `StreamServerInterceptor`
-// to the chain of interceptors. It only calls the method `Interceptor`
-// for each of them and append the return value to the chain.
-func (si *streamServerInterceptor) AddInterceptor(arr ...StreamServerInterceptor) StreamServerInterceptor {
-	si.lock.Lock()
-	defer si.lock.Unlock()
-	for _, i := range arr {
-		si.interceptors = append(si.interceptors, i.Interceptor())
+// AddInterceptor adds `arr` to the chain of interceptors by reference:
+// interceptors that are added to any of them later on are called as well.
+// Chains that are already referenced are not added again. They have
+// `PhaseDefault` as their priority.
+func (c *streamServerInterceptor) AddInterceptor(arr ...StreamServerInterceptor) StreamServerInterceptor {
+	children := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		children[idx] = i
 	}
-	return si
+	c.addChildren(children)
+	return c
+}
+
+// AddNamed adds `i` to the chain of interceptors under `name` with
+// `PhaseDefault` as its priority. If an interceptor has already been added
+// under `name`, it is replaced in place.
+func (c *streamServerInterceptor) AddNamed(name string, i grpc.StreamServerInterceptor) StreamServerInterceptor {
+	return c.AddNamedWithPriority(name, PhaseDefault, i)
+}
+
+// AddNamedWithPriority adds `i` to the chain of interceptors under `name` with
+// `priority` as its priority. If an interceptor has already been added under
+// `name`, it is replaced in place.
+func (c *streamServerInterceptor) AddNamedWithPriority(name string, priority Priority, i grpc.StreamServerInterceptor) StreamServerInterceptor {
+	c.addNamed(name, priority, i)
+	return c
+}
+
+// AddMiddleware adds the `grpc.StreamServerInterceptor` of each given middleware to the
+// chain with the priority of the middleware, under its name if it has one.
+// Middlewares that do not define any `grpc.StreamServerInterceptor` are ignored.
+func (c *streamServerInterceptor) AddMiddleware(arr ...Middleware) StreamServerInterceptor {
+	for _, m := range arr {
+		switch {
+		case m.StreamServer == nil:
+		case m.Name == "":
+			c.AddGRPCInterceptorWithPriority(m.Priority, m.StreamServer)
+		default:
+			c.AddNamedWithPriority(m.Name, m.Priority, m.StreamServer)
+		}
+	}
+	return c
+}
+
+// AddNamedInterceptor adds `i` to the chain of interceptors by reference under
+// `name`. If `i` is already referenced, nothing is done. Otherwise, if an
+// interceptor has already been added under `name`, it is replaced in place.
+func (c *streamServerInterceptor) AddNamedInterceptor(name string, i StreamServerInterceptor) StreamServerInterceptor {
+	c.addNamedChild(name, i)
+	return c
+}
+
+// Replace replaces the interceptor named `name` with `i`, keeping its name,
+// its priority and its position in the chain. It returns
+// `ErrInterceptorNotFound` if there is none.
+func (c *streamServerInterceptor) Replace(name string, i grpc.StreamServerInterceptor) error {
+	return c.replace(name, i)
+}
+
+// InsertBefore inserts `arr` right before the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *streamServerInterceptor) InsertBefore(name string, arr ...grpc.StreamServerInterceptor) error {
+	return c.insert(name, 0, streamServerInterceptors(arr))
+}
+
+// InsertAfter inserts `arr` right after the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *streamServerInterceptor) InsertAfter(name string, arr ...grpc.StreamServerInterceptor) error {
+	return c.insert(name, 1, streamServerInterceptors(arr))
+}
+
+// unaryServerInterceptor is a chain of `grpc.UnaryServerInterceptor`.
+type unaryServerInterceptor struct {
+	*chain
+}
+
+// unaryServerKind describes the chains of `grpc.UnaryServerInterceptor`.
+var unaryServerKind = &chainKind{
+	compile: func(arr []interface{}) interface{} {
+		interceptors := make([]grpc.UnaryServerInterceptor, len(arr))
+		for idx, i := range arr {
+			interceptors[idx] = i.(grpc.UnaryServerInterceptor)
+		}
+		return compileUnaryServerInterceptors(interceptors)
+	},
+	interceptor: func(child interface{}) interface{} {
+		return child.(UnaryServerInterceptor).Interceptor()
+	},
 }
 
 // NewUnaryServerInterceptor returns a new `UnaryServerInterceptor`.
 // It initializes its interceptor chain with `arr`.
 // This implementation is thread-safe.
+//
+// Interceptors are called by ascending priority and, for a given priority, in
+// the order they have been added.
 func NewUnaryServerInterceptor(arr ...grpc.UnaryServerInterceptor) UnaryServerInterceptor {
-	return &unaryServerInterceptor{
-		interceptors: arr,
-		lock:         &sync.RWMutex{},
+	return &unaryServerInterceptor{chain: newChain(unaryServerKind, unaryServerInterceptors(arr))}
+}
+
+// unaryServerInterceptors converts `arr` for the methods of `chain`.
+func unaryServerInterceptors(arr []grpc.UnaryServerInterceptor) []interface{} {
+	ret := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		ret[idx] = i
 	}
+	return ret
 }
 
-func chainUnaryServerInterceptor(current grpc.UnaryServerInterceptor, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) grpc.UnaryHandler {
+func chainUnaryServerInterceptor(arr []grpc.UnaryServerInterceptor, idx int, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) grpc.UnaryHandler {
+	if idx == len(arr) {
+		return handler
+	}
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
-		return current(ctx, req, info, next)
+		return arr[idx](ctx, req, info, chainUnaryServerInterceptor(arr, idx+1, info, handler))
+	}
+}
+
+// compileUnaryServerInterceptors chains `arr` into a single
+// `grpc.UnaryServerInterceptor`. The handler of each element is only built
+// when the previous element calls it.
+func compileUnaryServerInterceptors(arr []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	switch len(arr) {
+	case 0:
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctx, req)
+		}
+	case 1:
+		return arr[0]
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return arr[0](ctx, req, info, chainUnaryServerInterceptor(arr, 1, info, handler))
 	}
 }
 
@@ -121,35 +339,95 @@ func chainUnaryServerInterceptor(current grpc.UnaryServerInterceptor, info *grpc
 //
 // The `handler` passed to each interceptor is either the next interceptor or,
 // for the last element of the chain, the target method.
-func (ui *unaryServerInterceptor) Interceptor() grpc.UnaryServerInterceptor {
+//
+// The chain is compiled once and compiled again only when it or any of the
+// chains it references is modified.
+func (c *unaryServerInterceptor) Interceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		// TODO: Find a more efficient way
-		interceptor := handler
-		ui.lock.RLock()
-		for idx := len(ui.interceptors) - 1; idx >= 0; idx-- {
-			interceptor = chainUnaryServerInterceptor(ui.interceptors[idx], info, interceptor)
-		}
-		ui.lock.RUnlock()
-		return interceptor(ctx, req)
+		return c.compile().(grpc.UnaryServerInterceptor)(ctx, req, info, handler)
+	}
+}
+
+// AddGRPCInterceptor adds `arr` to the chain of interceptors with
+// `PhaseDefault` as their priority.
+func (c *unaryServerInterceptor) AddGRPCInterceptor(arr ...grpc.UnaryServerInterceptor) UnaryServerInterceptor {
+	return c.AddGRPCInterceptorWithPriority(PhaseDefault, arr...)
+}
+
+// AddGRPCInterceptorWithPriority adds `arr` to the chain of interceptors with
+// `priority` as their priority.
+func (c *unaryServerInterceptor) AddGRPCInterceptorWithPriority(priority Priority, arr ...grpc.UnaryServerInterceptor) UnaryServerInterceptor {
+	c.add(priority, unaryServerInterceptors(arr))
+	return c
+}
+
+// AddInterceptor adds `arr` to the chain of interceptors by reference:
+// interceptors that are added to any of them later on are called as well.
+// Chains that are already referenced are not added again. They have
+// `PhaseDefault` as their priority.
+func (c *unaryServerInterceptor) AddInterceptor(arr ...UnaryServerInterceptor) UnaryServerInterceptor {
+	children := make([]interface{}, len(arr))
+	for idx, i := range arr {
+		children[idx] = i
 	}
+	c.addChildren(children)
+	return c
+}
+
+// AddNamed adds `i` to the chain of interceptors under `name` with
+// `PhaseDefault` as its priority. If an interceptor has already been added
+// under `name`, it is replaced in place.
+func (c *unaryServerInterceptor) AddNamed(name string, i grpc.UnaryServerInterceptor) UnaryServerInterceptor {
+	return c.AddNamedWithPriority(name, PhaseDefault, i)
 }
 
-// AddGRPCInterceptor adds `arr` to the chain of interceptors.
-func (ui *unaryServerInterceptor) AddGRPCInterceptor(arr ...grpc.UnaryServerInterceptor) UnaryServerInterceptor {
-	ui.lock.Lock()
-	defer ui.lock.Unlock()
-	ui.interceptors = append(ui.interceptors, arr...)
-	return ui
+// AddNamedWithPriority adds `i` to the chain of interceptors under `name` with
+// `priority` as its priority. If an interceptor has already been added under
+// `name`, it is replaced in place.
+func (c *unaryServerInterceptor) AddNamedWithPriority(name string, priority Priority, i grpc.UnaryServerInterceptor) UnaryServerInterceptor {
+	c.addNamed(name, priority, i)
+	return c
 }
 
-// AddInterceptor is a convenient way for adding `UnaryServerInterceptor`
-// to the chain of interceptors. It only calls the method `Interceptor`
-// for each of them and append the return value to the chain.
-func (ui *unaryServerInterceptor) AddInterceptor(arr ...UnaryServerInterceptor) UnaryServerInterceptor {
-	ui.lock.Lock()
-	defer ui.lock.Unlock()
-	for _, i := range arr {
-		ui.interceptors = append(ui.interceptors, i.Interceptor())
+// AddMiddleware adds the `grpc.UnaryServerInterceptor` of each given middleware to the
+// chain with the priority of the middleware, under its name if it has one.
+// Middlewares that do not define any `grpc.UnaryServerInterceptor` are ignored.
+func (c *unaryServerInterceptor) AddMiddleware(arr ...Middleware) UnaryServerInterceptor {
+	for _, m := range arr {
+		switch {
+		case m.UnaryServer == nil:
+		case m.Name == "":
+			c.AddGRPCInterceptorWithPriority(m.Priority, m.UnaryServer)
+		default:
+			c.AddNamedWithPriority(m.Name, m.Priority, m.UnaryServer)
+		}
 	}
-	return ui
+	return c
+}
+
+// AddNamedInterceptor adds `i` to the chain of interceptors by reference under
+// `name`. If `i` is already referenced, nothing is done. Otherwise, if an
+// interceptor has already been added under `name`, it is replaced in place.
+func (c *unaryServerInterceptor) AddNamedInterceptor(name string, i UnaryServerInterceptor) UnaryServerInterceptor {
+	c.addNamedChild(name, i)
+	return c
+}
+
+// Replace replaces the interceptor named `name` with `i`, keeping its name,
+// its priority and its position in the chain. It returns
+// `ErrInterceptorNotFound` if there is none.
+func (c *unaryServerInterceptor) Replace(name string, i grpc.UnaryServerInterceptor) error {
+	return c.replace(name, i)
+}
+
+// InsertBefore inserts `arr` right before the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *unaryServerInterceptor) InsertBefore(name string, arr ...grpc.UnaryServerInterceptor) error {
+	return c.insert(name, 0, unaryServerInterceptors(arr))
+}
+
+// InsertAfter inserts `arr` right after the interceptor named `name`, with
+// the same priority. It returns `ErrInterceptorNotFound` if there is none.
+func (c *unaryServerInterceptor) InsertAfter(name string, arr ...grpc.UnaryServerInterceptor) error {
+	return c.insert(name, 1, unaryServerInterceptors(arr))
 }
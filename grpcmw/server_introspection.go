@@ -0,0 +1,36 @@
+package grpcmw
+
+import "io"
+
+// WalkServerFunc is the type of the function called for each level visited
+// when walking a tree of `ServerInterceptor`. `path` is the list of indexes
+// leading to `lvl` from the level the walk started from.
+type WalkServerFunc func(path []string, lvl ServerInterceptor) error
+
+// WalkServerInterceptor calls `fn` for `lvl` and then, if it is a
+// `ServerInterceptorRegister`, recursively for each of its sublevels sorted by
+// index. It stops at the first error returned by `fn` and returns it.
+func WalkServerInterceptor(lvl ServerInterceptor, fn WalkServerFunc) error {
+	return walkLevel(serverSide, []string{}, lvl, func(path []string, lvl level) error {
+		return fn(path, lvl.(ServerInterceptor))
+	})
+}
+
+// DescribeServerInterceptor returns the description of `lvl` and of all its
+// sublevels.
+func DescribeServerInterceptor(lvl ServerInterceptor) *LevelInfo {
+	return describeLevels(serverSide, lvl)
+}
+
+// DumpServerInterceptor writes a human-readable tree view of `lvl` and of all
+// its sublevels to `w`. Like `Describe`, it shows the structure of the chains of
+// interceptors rather than the order in which they are called.
+func DumpServerInterceptor(w io.Writer, lvl ServerInterceptor) error {
+	return DescribeServerInterceptor(lvl).WriteText(w)
+}
+
+// DumpServerInterceptorJSON writes the JSON form of the description of `lvl`
+// and of all its sublevels to `w`.
+func DumpServerInterceptorJSON(w io.Writer, lvl ServerInterceptor) error {
+	return DescribeServerInterceptor(lvl).WriteJSON(w)
+}
@@ -1,10 +1,6 @@
 package grpcmw
 
-import (
-	"sync"
-
-	"google.golang.org/grpc"
-)
+import "google.golang.org/grpc"
 
 // ServerInterceptor represent a server interceptor that uses both
 // `UnaryServerInterceptor` and `StreamServerInterceptor` and that can be
@@ -15,6 +11,14 @@ type ServerInterceptor interface {
 	// AddUnaryInterceptor is a convenient way for adding `UnaryServerInterceptor`
 	// to the chain of unary interceptors.
 	AddUnaryInterceptor(i ...UnaryServerInterceptor) ServerInterceptor
+	// AddGRPCUnaryInterceptorWithPriority adds given unary interceptors to the
+	// chain with `priority` as their priority.
+	AddGRPCUnaryInterceptorWithPriority(priority Priority, i ...grpc.UnaryServerInterceptor) ServerInterceptor
+	// AddNamedGRPCUnaryInterceptor adds the given unary interceptor to the
+	// chain under `name`.
+	AddNamedGRPCUnaryInterceptor(name string, i grpc.UnaryServerInterceptor) ServerInterceptor
+	// ReplaceGRPCUnaryInterceptor replaces the unary interceptor named `name`.
+	ReplaceGRPCUnaryInterceptor(name string, i grpc.UnaryServerInterceptor) error
 	// UnaryServerInterceptor returns the chain of unary interceptors.
 	UnaryServerInterceptor() UnaryServerInterceptor
 	// AddGRPCStreamInterceptor adds given stream interceptors to the chain.
@@ -22,12 +26,37 @@ type ServerInterceptor interface {
 	// AddStreamInterceptor is a convenient way for adding
 	// `StreamServerInterceptor` to the chain of stream interceptors.
 	AddStreamInterceptor(i ...StreamServerInterceptor) ServerInterceptor
+	// AddGRPCStreamInterceptorWithPriority adds given stream interceptors to the
+	// chain with `priority` as their priority.
+	AddGRPCStreamInterceptorWithPriority(priority Priority, i ...grpc.StreamServerInterceptor) ServerInterceptor
+	// AddNamedGRPCStreamInterceptor adds the given stream interceptor to the
+	// chain under `name`.
+	AddNamedGRPCStreamInterceptor(name string, i grpc.StreamServerInterceptor) ServerInterceptor
+	// ReplaceGRPCStreamInterceptor replaces the stream interceptor named `name`.
+	ReplaceGRPCStreamInterceptor(name string, i grpc.StreamServerInterceptor) error
 	// StreamServerInterceptor returns the chain of stream interceptors.
 	StreamServerInterceptor() StreamServerInterceptor
-	// Merge merges the given interceptors with the current interceptor.
+	// RemoveInterceptor removes the unary and stream interceptors named `name`.
+	RemoveInterceptor(name string) error
+	// AddMiddleware adds the server interceptors of the given middlewares to
+	// the chains of unary and stream interceptors.
+	AddMiddleware(m ...Middleware) ServerInterceptor
+	// Merge merges the given interceptors with the current interceptor. They
+	// are kept by reference.
 	Merge(interceptors ...ServerInterceptor) ServerInterceptor
 	// Index returns the index of the `ServerInterceptor`.
 	Index() string
+	// Exclude excludes the interceptors and chains named `names` from the
+	// parent levels of the interceptor and from the levels bound to patterns
+	// when a route goes through it.
+	Exclude(names ...string) ServerInterceptor
+	// Excluded returns the names excluded by the interceptor.
+	Excluded() []string
+	// Freeze makes the interceptor, its chains of interceptors and its
+	// sublevels immutable (see `FreezeMode`).
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the interceptor has been frozen.
+	Frozen() bool
 }
 
 // ServerInterceptorRegister represents a register of `ServerInterceptor`,
@@ -36,22 +65,35 @@ type ServerInterceptor interface {
 type ServerInterceptorRegister interface {
 	ServerInterceptor
 	// Register registers `level` at the index returned by its method `Index`.
-	Register(level ServerInterceptor)
+	// It fails with `ErrFrozen` if the register has been frozen.
+	Register(level ServerInterceptor) error
+	// Unregister removes the level registered at the index `key`. It returns
+	// `ErrInterceptorNotFound` if there is no such level and `ErrFrozen` if the
+	// register has been frozen.
+	Unregister(key string) error
+	// Keys returns the sorted indexes of the registered levels.
+	Keys() []string
+	// Reset removes all the registered levels. It fails with `ErrFrozen` if
+	// the register has been frozen.
+	Reset() error
 	// Get returns the `ServerInterceptor` registered at the index `key`. If
 	// nothing is found, it returns (nil, false).
 	Get(key string) (ServerInterceptor, bool)
+	// Walk calls `fn` for the register and recursively for all its sublevels
+	// (see `WalkServerInterceptor`).
+	Walk(fn WalkServerFunc) error
 }
 
 type lowerServerInterceptor struct {
+	*levelBase
 	unaries UnaryServerInterceptor
 	streams StreamServerInterceptor
-	index   string
 }
 
+// higherServerInterceptorLevel holds its sublevels in a `levelTree`.
 type higherServerInterceptorLevel struct {
 	ServerInterceptor
-	sublevels map[string]ServerInterceptor
-	lock      *sync.RWMutex
+	tree *levelTree
 }
 
 // NewServerInterceptor initializes a new `ServerInterceptor` with `index`
@@ -60,17 +102,12 @@ type higherServerInterceptorLevel struct {
 // This implementation is thread-safe.
 func NewServerInterceptor(index string) ServerInterceptor {
 	return &lowerServerInterceptor{
-		unaries: NewUnaryServerInterceptor(),
-		streams: NewStreamServerInterceptor(),
-		index:   index,
+		levelBase: newLevelBase(index),
+		unaries:   NewUnaryServerInterceptor(),
+		streams:   NewStreamServerInterceptor(),
 	}
 }
 
-// Index returns the index of the `ServerInterceptor`.
-func (l lowerServerInterceptor) Index() string {
-	return l.index
-}
-
 // AddGRPCUnaryInterceptor calls `AddGRPCInterceptor` of the underlying
 // `UnaryServerInterceptor`. It returns the current instance of
 // `ServerInterceptor` to allow chaining.
@@ -80,13 +117,35 @@ func (l *lowerServerInterceptor) AddGRPCUnaryInterceptor(arr ...grpc.UnaryServer
 }
 
 // AddUnaryInterceptor calls `AddInterceptor` of the underlying
-// `UnaryServerInterceptor`. It returns the current instance of
+// `UnaryServerInterceptor`, which keeps `arr` by reference. It returns the current instance of
 // `ServerInterceptor` to allow chaining.
 func (l *lowerServerInterceptor) AddUnaryInterceptor(arr ...UnaryServerInterceptor) ServerInterceptor {
 	l.unaries.AddInterceptor(arr...)
 	return l
 }
 
+// AddGRPCUnaryInterceptorWithPriority calls `AddGRPCInterceptorWithPriority` of
+// the underlying `UnaryServerInterceptor`. It returns the current instance of
+// `ServerInterceptor` to allow chaining.
+func (l *lowerServerInterceptor) AddGRPCUnaryInterceptorWithPriority(priority Priority, arr ...grpc.UnaryServerInterceptor) ServerInterceptor {
+	l.unaries.AddGRPCInterceptorWithPriority(priority, arr...)
+	return l
+}
+
+// AddNamedGRPCUnaryInterceptor calls `AddNamed` of the underlying
+// `UnaryServerInterceptor`. It returns the current instance of
+// `ServerInterceptor` to allow chaining.
+func (l *lowerServerInterceptor) AddNamedGRPCUnaryInterceptor(name string, i grpc.UnaryServerInterceptor) ServerInterceptor {
+	l.unaries.AddNamed(name, i)
+	return l
+}
+
+// ReplaceGRPCUnaryInterceptor calls `Replace` of the underlying
+// `UnaryServerInterceptor`.
+func (l *lowerServerInterceptor) ReplaceGRPCUnaryInterceptor(name string, i grpc.UnaryServerInterceptor) error {
+	return l.unaries.Replace(name, i)
+}
+
 // UnaryServerInterceptor returns the underlying instance of
 // `UnaryServerInterceptor`.
 func (l *lowerServerInterceptor) UnaryServerInterceptor() UnaryServerInterceptor {
@@ -101,25 +160,93 @@ func (l *lowerServerInterceptor) AddGRPCStreamInterceptor(arr ...grpc.StreamServ
 	return l
 }
 
-// AddStreamInterceptor calls `AddGRPCInterceptor` of the underlying
-// `StreamServerInterceptor`. It returns the current instance of
+// AddStreamInterceptor calls `AddInterceptor` of the underlying
+// `StreamServerInterceptor`, which keeps `arr` by reference. It returns the current instance of
 // `ServerInterceptor` to allow chaining.
 func (l *lowerServerInterceptor) AddStreamInterceptor(arr ...StreamServerInterceptor) ServerInterceptor {
 	l.streams.AddInterceptor(arr...)
 	return l
 }
 
+// AddGRPCStreamInterceptorWithPriority calls `AddGRPCInterceptorWithPriority` of
+// the underlying `StreamServerInterceptor`. It returns the current instance of
+// `ServerInterceptor` to allow chaining.
+func (l *lowerServerInterceptor) AddGRPCStreamInterceptorWithPriority(priority Priority, arr ...grpc.StreamServerInterceptor) ServerInterceptor {
+	l.streams.AddGRPCInterceptorWithPriority(priority, arr...)
+	return l
+}
+
+// AddNamedGRPCStreamInterceptor calls `AddNamed` of the underlying
+// `StreamServerInterceptor`. It returns the current instance of
+// `ServerInterceptor` to allow chaining.
+func (l *lowerServerInterceptor) AddNamedGRPCStreamInterceptor(name string, i grpc.StreamServerInterceptor) ServerInterceptor {
+	l.streams.AddNamed(name, i)
+	return l
+}
+
+// ReplaceGRPCStreamInterceptor calls `Replace` of the underlying
+// `StreamServerInterceptor`.
+func (l *lowerServerInterceptor) ReplaceGRPCStreamInterceptor(name string, i grpc.StreamServerInterceptor) error {
+	return l.streams.Replace(name, i)
+}
+
 // StreamServerInterceptor returns the underlying instance of
 // `StreamServerInterceptor`.
 func (l *lowerServerInterceptor) StreamServerInterceptor() StreamServerInterceptor {
 	return l.streams
 }
 
-// Merge merges the given interceptors with the current interceptor.
+// RemoveInterceptor removes the interceptors named `name` from both the
+// underlying `UnaryServerInterceptor` and `StreamServerInterceptor`. It returns
+// `ErrInterceptorNotFound` only if neither of them had such an interceptor.
+func (l *lowerServerInterceptor) RemoveInterceptor(name string) error {
+	return removeError(l.unaries.Remove(name), l.streams.Remove(name))
+}
+
+// Freeze freezes the underlying `UnaryServerInterceptor` and
+// `StreamServerInterceptor`, as well as the names excluded by the interceptor.
+func (l *lowerServerInterceptor) Freeze(mode FreezeMode) {
+	l.freeze(mode)
+	l.unaries.Freeze(mode)
+	l.streams.Freeze(mode)
+}
+
+// Exclude excludes the interceptors and chains named `names` from the parent
+// levels of the interceptor, as well as from the levels bound to patterns that
+// match the route, when a route goes through it. For instance, excluding
+// "auth" at the level of the method "/grpc.health.v1.Health/Check" prevents
+// the interceptor named "auth" of the global level from being called for this
+// method. It returns the current instance of `ServerInterceptor` to allow
+// chaining.
+func (l *lowerServerInterceptor) Exclude(names ...string) ServerInterceptor {
+	l.exclude(names)
+	return l
+}
+
+// Frozen returns whether the underlying chains of interceptors have been
+// frozen.
+func (l *lowerServerInterceptor) Frozen() bool {
+	return l.unaries.Frozen() && l.streams.Frozen()
+}
+
+// AddMiddleware calls `AddMiddleware` of both the underlying
+// `UnaryServerInterceptor` and `StreamServerInterceptor`. It returns the current
+// instance of `ServerInterceptor` to allow chaining.
+func (l *lowerServerInterceptor) AddMiddleware(arr ...Middleware) ServerInterceptor {
+	l.unaries.AddMiddleware(arr...)
+	l.streams.AddMiddleware(arr...)
+	return l
+}
+
+// Merge merges the given interceptors with the current interceptor. Their
+// chains of unary and stream interceptors are added by reference under their
+// index: interceptors added to them later on are also called. Merging the same
+// interceptor twice has no effect, but interceptors that share an index are all
+// merged.
 func (l *lowerServerInterceptor) Merge(interceptors ...ServerInterceptor) ServerInterceptor {
 	for _, interceptor := range interceptors {
-		l.AddUnaryInterceptor(interceptor.UnaryServerInterceptor()).
-			AddStreamInterceptor(interceptor.StreamServerInterceptor())
+		coreOf(l.unaries).merge(interceptor.Index(), interceptor.UnaryServerInterceptor())
+		coreOf(l.streams).merge(interceptor.Index(), interceptor.StreamServerInterceptor())
 	}
 	return l
 }
@@ -130,24 +257,67 @@ func (l *lowerServerInterceptor) Merge(interceptors ...ServerInterceptor) Server
 func NewServerInterceptorRegister(index string) ServerInterceptorRegister {
 	return &higherServerInterceptorLevel{
 		ServerInterceptor: NewServerInterceptor(index),
-		sublevels:         make(map[string]ServerInterceptor),
-		lock:              &sync.RWMutex{},
+		tree:              newLevelTree(),
 	}
 }
 
 // Get returns the `ServerInterceptor` registered at the index `key`. If nothing
 // is found, it returns (nil, false).
-func (l higherServerInterceptorLevel) Get(key string) (interceptor ServerInterceptor, exists bool) {
-	l.lock.RLock()
-	defer l.lock.RUnlock()
-	interceptor, exists = l.sublevels[key]
-	return
+func (l *higherServerInterceptorLevel) Get(key string) (ServerInterceptor, bool) {
+	sub, exists := l.tree.get(key)
+	if !exists {
+		return nil, false
+	}
+	return sub.(ServerInterceptor), true
 }
 
 // Register registers `level` at the index returned by its method `Index`.
 // It overwrites any interceptor that has already been registered at this index.
-func (l *higherServerInterceptorLevel) Register(level ServerInterceptor) {
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	l.sublevels[level.Index()] = level
+// It fails with `ErrFrozen` if the register has been frozen.
+func (l *higherServerInterceptorLevel) Register(level ServerInterceptor) error {
+	return l.tree.register(level)
+}
+
+// Unregister removes the level registered at the index `key`. It returns
+// `ErrInterceptorNotFound` if there is no such level and `ErrFrozen` if the
+// register has been frozen.
+func (l *higherServerInterceptorLevel) Unregister(key string) error {
+	return l.tree.unregister(key)
+}
+
+// Keys returns the sorted indexes of the registered levels.
+func (l *higherServerInterceptorLevel) Keys() []string {
+	return l.tree.keys()
+}
+
+// Reset removes all the registered levels. The interceptors of the register
+// itself are kept. It fails with `ErrFrozen` if the register has been frozen.
+func (l *higherServerInterceptorLevel) Reset() error {
+	return l.tree.reset()
+}
+
+// Freeze makes the register immutable, as well as its chains of interceptors
+// and all its sublevels. Registering a new level afterwards either fails with
+// `ErrFrozen` or panics, depending on `mode`.
+func (l *higherServerInterceptorLevel) Freeze(mode FreezeMode) {
+	if l.tree.freeze(mode) {
+		l.ServerInterceptor.Freeze(mode)
+	}
+}
+
+// Frozen returns whether the register has been frozen.
+func (l *higherServerInterceptorLevel) Frozen() bool {
+	return l.tree.isFrozen()
+}
+
+// levelTree returns the sublevels of the register.
+func (l *higherServerInterceptorLevel) levelTree() *levelTree {
+	return l.tree
+}
+
+// Walk calls `fn` for the register and then recursively for each of its
+// sublevels sorted by index. It stops at the first error returned by `fn` and
+// returns it.
+func (l *higherServerInterceptorLevel) Walk(fn WalkServerFunc) error {
+	return WalkServerInterceptor(l, fn)
 }
@@ -1,8 +1,7 @@
 package grpcmw
 
 import (
-	"errors"
-	"fmt"
+	"sync/atomic"
 
 	"golang.org/x/net/context"
 
@@ -15,18 +14,91 @@ import (
 type ServerRouter interface {
 	// GetRegister returns the interceptor register of the router.
 	GetRegister() ServerInterceptorRegister
-	// SetRegister sets the interceptor register of the router.
-	SetRegister(reg ServerInterceptorRegister)
+	// SetRegister sets the interceptor register of the router. It fails with
+	// `ErrFrozen` if the router has been frozen.
+	SetRegister(reg ServerInterceptorRegister) error
+	// Reload sets up a new router with `build` and sets its interceptor
+	// register and its patterns as those of the router, only if it succeeds
+	// and the chains of every route it defines can be compiled.
+	Reload(build func(staging ServerRouter) error) error
 	// UnaryResolver returns a `grpc.UnaryServerInterceptor` that uses the
 	// appropriate chain of interceptors with the given unary gRPC request.
 	UnaryResolver() grpc.UnaryServerInterceptor
 	// StreamResolver returns a `grpc.StreamServerInterceptor` that uses the
 	// appropriate chain of interceptors with the given stream gRPC request.
 	StreamResolver() grpc.StreamServerInterceptor
+	// ServerOptions returns the options that install both `UnaryResolver` and
+	// `StreamResolver` on a `grpc.Server`.
+	ServerOptions() []grpc.ServerOption
+	// CheckWiring checks that the resolvers of the router needed by the
+	// methods of the services registered on `srv` have been installed.
+	CheckWiring(srv *grpc.Server, mode WiringMode) error
+	// Resolve returns the description of the interceptors that are called for
+	// the given route, applying the policy for unknown routes, without calling
+	// any of them.
+	Resolve(route string) (*RouteInfo, error)
+	// AddPattern adds `lvl` as a level that applies to every route matching
+	// `pattern` (e.g. "/pkg.*/Get*").
+	AddPattern(pattern string, lvl ServerInterceptor) error
+	// RegisterPackage returns the register of the level of the protobuf
+	// package `pkg`, registering it if needed. `created` is true the first
+	// time the level of `pkg` is requested, unless it has been registered
+	// otherwise.
+	RegisterPackage(pkg string) (reg ServerInterceptorRegister, created bool)
+	// Freeze freezes the interceptor register of the router and compiles the
+	// chains of interceptors of every route it defines once and for all.
+	Freeze(mode FreezeMode)
+	// Frozen returns whether the router has been frozen.
+	Frozen() bool
 }
 
+// serverRouter is the `serverRouter` implementation of the shared router (see
+// `router`), which only converts levels and interceptors from and to their
+// server types.
 type serverRouter struct {
-	interceptors ServerInterceptorRegister
+	*router
+}
+
+// serverSide describes the server levels to the shared router.
+var serverSide = &side{
+	registerType: "grpcmw.ServerInterceptorRegister",
+	unary:        unaryServerKind,
+	stream:       streamServerKind,
+	chains: func(lvl level) (interface{}, interface{}) {
+		server := lvl.(ServerInterceptor)
+		return server.UnaryServerInterceptor(), server.StreamServerInterceptor()
+	},
+	isRegister: func(lvl level) bool {
+		_, ok := lvl.(ServerInterceptorRegister)
+		return ok
+	},
+	get: func(reg level, key string) (level, bool) {
+		sub, exists := reg.(ServerInterceptorRegister).Get(key)
+		return sub, exists
+	},
+	register: func(reg, sub level) error {
+		return reg.(ServerInterceptorRegister).Register(sub.(ServerInterceptor))
+	},
+	newRegister: func(index string) level {
+		return NewServerInterceptorRegister(index)
+	},
+	walk: func(reg level, fn func(path []string, lvl level) error) error {
+		return reg.(ServerInterceptorRegister).Walk(func(path []string, lvl ServerInterceptor) error {
+			return fn(path, lvl)
+		})
+	},
+	reject: func() (interface{}, interface{}) {
+		unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return nil, grpc.Errorf(codes.Unimplemented, "Unknown route %s", info.FullMethod)
+		}
+		stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return grpc.Errorf(codes.Unimplemented, "Unknown route %s", info.FullMethod)
+		}
+		return grpc.UnaryServerInterceptor(unary), grpc.StreamServerInterceptor(stream)
+	},
+	fallback: func(m Middleware) (interface{}, interface{}) {
+		return NewUnaryServerInterceptor().AddMiddleware(m), NewStreamServerInterceptor().AddMiddleware(m)
+	},
 }
 
 // NewServerRouter initializes a `ServerRouter`.
@@ -43,87 +115,148 @@ type serverRouter struct {
 //     a method from the corresponding service.
 //   - the method level: these are the interceptors called at each request to
 //     the specific method.
-func NewServerRouter() ServerRouter {
+//
+// How requests to unknown routes are handled can be set with
+// `WithUnknownRoutePolicy`.
+//
+// With `WithNestedPackages`, each segment of the package is a level of its own
+// instead of a single package level.
+//
+// Levels can also be bound to route patterns (see `AddPattern`).
+//
+// The chain of interceptors of a known route is compiled the first time the
+// route is requested and cached until any level or chain of interceptors is
+// modified.
+func NewServerRouter(opts ...RouterOption) ServerRouter {
 	return &serverRouter{
-		interceptors: NewServerInterceptorRegister("global"),
-	}
-}
-
-func resolveServerInterceptorRec(pathTokens []string, lvl ServerInterceptor, cb func(lvl ServerInterceptor), force bool) (ServerInterceptor, error) {
-	if cb != nil {
-		cb(lvl)
-	}
-	if len(pathTokens) == 0 || len(pathTokens[0]) == 0 {
-		return lvl, nil
-	}
-	reg, ok := lvl.(ServerInterceptorRegister)
-	if !ok {
-		return nil, fmt.Errorf("Level %s does not implement grpcmw.ServerInterceptorRegister", lvl.Index())
-	}
-	sub, exists := reg.Get(pathTokens[0])
-	if !exists {
-		if force {
-			if len(pathTokens) == 1 {
-				sub = NewServerInterceptor(pathTokens[0])
-			} else {
-				sub = NewServerInterceptorRegister(pathTokens[0])
-			}
-			reg.Register(sub)
-		} else {
-			return nil, nil
-		}
-	}
-	return resolveServerInterceptorRec(pathTokens[1:], sub, cb, force)
-}
-
-func resolveServerInterceptor(route string, lvl ServerInterceptor, cb func(lvl ServerInterceptor), force bool) (ServerInterceptor, error) {
-	// TODO: Find a more efficient way to resolve the route
-	matchs := routeRegexp.FindStringSubmatch(route)
-	if len(matchs) == 0 {
-		return nil, errors.New("Invalid route")
+		router: newRouter(serverSide, NewServerInterceptorRegister("global"), newRouterOptions(opts)),
 	}
-	return resolveServerInterceptorRec(matchs[1:], lvl, cb, force)
 }
 
 // UnaryResolver returns a `grpc.UnaryServerInterceptor` that uses the
 // appropriate chain of interceptors with the given gRPC request.
 func (r *serverRouter) UnaryResolver() grpc.UnaryServerInterceptor {
+	atomic.StoreInt32(&r.unaryWired, 1)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		// TODO: Find a more efficient way to chain the interceptors
-		interceptor := NewUnaryServerInterceptor()
-		_, err := resolveServerInterceptor(info.FullMethod, r.interceptors, func(lvl ServerInterceptor) {
-			interceptor.AddInterceptor(lvl.UnaryServerInterceptor())
-		}, false)
+		route, err := r.resolve(info.FullMethod)
 		if err != nil {
 			return nil, grpc.Errorf(codes.Internal, err.Error())
 		}
-		return interceptor.Interceptor()(ctx, req, info, handler)
+		return route.unary.(grpc.UnaryServerInterceptor)(ctx, req, info, handler)
 	}
 }
 
 // StreamResolver returns a `grpc.StreamServerInterceptor` that uses the
 // appropriate chain of interceptors with the given stream gRPC request.
 func (r *serverRouter) StreamResolver() grpc.StreamServerInterceptor {
+	atomic.StoreInt32(&r.streamWired, 1)
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		// TODO: Find a more efficient way to chain the interceptors
-		interceptor := NewStreamServerInterceptor()
-		_, err := resolveServerInterceptor(info.FullMethod, r.interceptors, func(lvl ServerInterceptor) {
-			interceptor.AddInterceptor(lvl.StreamServerInterceptor())
-		}, false)
+		route, err := r.resolve(info.FullMethod)
 		if err != nil {
 			return grpc.Errorf(codes.Internal, err.Error())
 		}
-		return interceptor.Interceptor()(srv, ss, info, handler)
+		return route.stream.(grpc.StreamServerInterceptor)(srv, ss, info, handler)
 	}
 }
 
+// ServerOptions returns the options that install both `UnaryResolver` and
+// `StreamResolver` on a `grpc.Server`, so that streaming methods are not left
+// without interceptors:
+//
+//	server := grpc.NewServer(router.ServerOptions()...)
+func (r *serverRouter) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(r.UnaryResolver()),
+		grpc.StreamInterceptor(r.StreamResolver()),
+	}
+}
+
+// CheckWiring checks that the resolvers of the router have been requested (see
+// `UnaryResolver`, `StreamResolver` and `ServerOptions`) for all the methods of
+// the services registered on `srv` that have interceptors of the corresponding
+// type. It is meant to be called once the services have been registered and
+// before `srv` starts serving. Depending on `mode`, the routes whose
+// interceptors would never be called are reported by returning an error,
+// logging it or panicking with it.
+func (r *serverRouter) CheckWiring(srv *grpc.Server, mode WiringMode) error {
+	var unaryRoutes, streamRoutes []string
+	unary, stream := serviceRoutes(srv)
+	if atomic.LoadInt32(&r.unaryWired) == 0 {
+		for _, route := range unary {
+			if info, err := r.Resolve(route); err == nil && len(info.Unary) > 0 {
+				unaryRoutes = append(unaryRoutes, route)
+			}
+		}
+	}
+	if atomic.LoadInt32(&r.streamWired) == 0 {
+		for _, route := range stream {
+			if info, err := r.Resolve(route); err == nil && len(info.Stream) > 0 {
+				streamRoutes = append(streamRoutes, route)
+			}
+		}
+	}
+	return wiringError(mode, unaryRoutes, streamRoutes)
+}
+
 // GetRegister returns the underlying `ServerInterceptorRegister` which is the
 // global level in the interceptor chain.
 func (r *serverRouter) GetRegister() ServerInterceptorRegister {
-	return r.interceptors
+	return r.loadState().register.(ServerInterceptorRegister)
+}
+
+// SetRegister sets the interceptor register of the router. The chains of
+// interceptors of the routes it defines are compiled before it is set, so that
+// requests never wait for them to be compiled. Requests that are being handled
+// keep on using the chains of interceptors they started with. It fails with
+// `ErrFrozen` if the router has been frozen.
+func (r *serverRouter) SetRegister(reg ServerInterceptorRegister) error {
+	return r.swap(reg, nil, false)
+}
+
+// Reload sets up `staging`, a new router with the same options and an empty
+// global level, with `build`. It then sets the interceptor register of
+// `staging` as the register of the router (see `SetRegister`) and its levels
+// bound to patterns as those of the router, so that `build` can call
+// `RegisterPackage`, `AddPattern` or the generated `RegisterServerInterceptors`
+// on it. The router is left untouched if `build` fails or if the chains of
+// interceptors of any route defined by the new register cannot be compiled
+// (e.g. a service level that is not a register). It fails with `ErrFrozen` if
+// the router has been frozen. `staging` must not be used once `build` returns.
+func (r *serverRouter) Reload(build func(staging ServerRouter) error) error {
+	return r.reload(func(staging *router) error {
+		return build(&serverRouter{router: staging})
+	})
+}
+
+// AddPattern adds `lvl` as a level that applies to every route matching
+// `pattern`, where `*` matches any sequence of characters except '/'. For
+// instance, "/company.billing.*/*" matches all the methods of the services of
+// the package "company.billing" and of its subpackages, and "/*/List*" all the
+// methods whose name starts with "List". Only the stream interceptors of a
+// level are called for streaming methods, so "/*/*" along with stream
+// interceptors only applies to streaming methods.
+//
+// The interceptors of the levels bound to patterns are called after the ones of
+// the global, package, service and method levels, in the order in which the
+// patterns have been added. They are still subject to the names excluded by
+// these levels (see `Exclude`). The pattern is compiled once, so that matching
+// a route never allocates. It fails with `ErrFrozen` if the router has been
+// frozen.
+func (r *serverRouter) AddPattern(pattern string, lvl ServerInterceptor) error {
+	return r.addPattern(pattern, lvl)
 }
 
-// SetRegister sets the interceptor register of the router.
-func (r *serverRouter) SetRegister(reg ServerInterceptorRegister) {
-	r.interceptors = reg
+// RegisterPackage returns the register of the level of the protobuf package
+// `pkg`, registering it along with its parent levels if needed. `created` is
+// true the first time the level of `pkg` is requested, including when it has
+// only been registered as the parent of a subpackage so far, so that the
+// caller knows it has to set it up. It panics if a level that is not a
+// `ServerInterceptorRegister` is already registered on the path of `pkg`. If the
+// register of the router has been frozen, the level is not registered: the
+// modification is ignored as for any frozen register (see `FreezeMode`), and
+// the returned register is detached from the router and never reported as
+// created.
+func (r *serverRouter) RegisterPackage(pkg string) (reg ServerInterceptorRegister, created bool) {
+	lvl, created := r.registerPackage(pkg)
+	return lvl.(ServerInterceptorRegister), created
 }
@@ -0,0 +1,152 @@
+package grpcmw
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// level is what a register needs to know about the levels it holds. It is
+// implemented by both `ServerInterceptor` and `ClientInterceptor`.
+type level interface {
+	Index() string
+	Excluded() []string
+	Freeze(mode FreezeMode)
+}
+
+// treeLevel is implemented by the registers of this package, whose sublevels
+// are held by a `levelTree`.
+type treeLevel interface {
+	levelTree() *levelTree
+}
+
+// levelTree holds the sublevels of a register. It is shared by server and
+// client registers, which only convert levels from and to their own type.
+// It publishes a new immutable map of sublevels on each modification, so that
+// lookups never lock. Writers are serialized by `lock`.
+type levelTree struct {
+	sublevels *atomic.Value // map[string]level
+	lock      *sync.Mutex
+	frozen    bool
+	mode      FreezeMode
+}
+
+func newLevelTree() *levelTree {
+	t := &levelTree{
+		sublevels: &atomic.Value{},
+		lock:      &sync.Mutex{},
+	}
+	t.sublevels.Store(make(map[string]level))
+	return t
+}
+
+// load returns the current map of sublevels. It must not be modified.
+func (t *levelTree) load() map[string]level {
+	return t.sublevels.Load().(map[string]level)
+}
+
+// get returns the level registered at the index `key`.
+func (t *levelTree) get(key string) (lvl level, exists bool) {
+	lvl, exists = t.load()[key]
+	return
+}
+
+// update publishes the map of sublevels returned by `fn` from the current one.
+// It fails if the tree has been frozen.
+func (t *levelTree) update(fn func(current map[string]level) (map[string]level, error)) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.frozen {
+		return frozenError(t.mode)
+	}
+	sublevels, err := fn(t.load())
+	if err != nil {
+		return err
+	}
+	t.sublevels.Store(sublevels)
+	invalidateRoutes()
+	return nil
+}
+
+// register registers `lvl` at its index, overwriting any level that has
+// already been registered at this index.
+func (t *levelTree) register(lvl level) error {
+	return t.update(func(current map[string]level) (map[string]level, error) {
+		sublevels := make(map[string]level, len(current)+1)
+		for key, sub := range current {
+			sublevels[key] = sub
+		}
+		sublevels[lvl.Index()] = lvl
+		return sublevels, nil
+	})
+}
+
+// unregister removes the level registered at the index `key`.
+func (t *levelTree) unregister(key string) error {
+	return t.update(func(current map[string]level) (map[string]level, error) {
+		if _, exists := current[key]; !exists {
+			return nil, ErrInterceptorNotFound
+		}
+		sublevels := make(map[string]level, len(current)-1)
+		for index, sub := range current {
+			if index != key {
+				sublevels[index] = sub
+			}
+		}
+		return sublevels, nil
+	})
+}
+
+// reset removes all the registered levels.
+func (t *levelTree) reset() error {
+	return t.update(func(map[string]level) (map[string]level, error) {
+		return make(map[string]level), nil
+	})
+}
+
+// keys returns the sorted indexes of the registered levels.
+func (t *levelTree) keys() []string {
+	current := t.load()
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sorted returns the registered levels sorted by index.
+func (t *levelTree) sorted() []level {
+	current := t.load()
+	sublevels := make([]level, 0, len(current))
+	for _, sub := range current {
+		sublevels = append(sublevels, sub)
+	}
+	sort.Slice(sublevels, func(i, j int) bool {
+		return sublevels[i].Index() < sublevels[j].Index()
+	})
+	return sublevels
+}
+
+// freeze prevents any further modification of the tree and freezes all the
+// registered levels. It returns false if the tree was already frozen.
+func (t *levelTree) freeze(mode FreezeMode) bool {
+	t.lock.Lock()
+	if t.frozen {
+		t.lock.Unlock()
+		return false
+	}
+	t.frozen, t.mode = true, mode
+	t.lock.Unlock()
+	for _, sub := range t.load() {
+		sub.Freeze(mode)
+	}
+	return true
+}
+
+// isFrozen returns whether the tree has been frozen.
+func (t *levelTree) isFrozen() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.frozen
+}
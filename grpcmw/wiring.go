@@ -0,0 +1,66 @@
+package grpcmw
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/grpclog"
+)
+
+// WiringMode defines how routers whose resolvers are not all installed are
+// reported by `CheckWiring`.
+type WiringMode int
+
+const (
+	// WiringError makes `CheckWiring` return the error.
+	WiringError WiringMode = iota
+	// WiringWarn makes `CheckWiring` log the error with `grpclog`.
+	WiringWarn
+	// WiringPanic makes `CheckWiring` panic with the error.
+	WiringPanic
+)
+
+// wiringError reports the routes or levels whose interceptors are never
+// called, as the resolver of their type has not been requested, depending on
+// `mode`.
+func wiringError(mode WiringMode, unary, stream []string) error {
+	if len(unary) == 0 && len(stream) == 0 {
+		return nil
+	}
+	var msgs []string
+	if len(unary) > 0 {
+		sort.Strings(unary)
+		msgs = append(msgs, fmt.Sprintf("unary interceptors of %s are never called as UnaryResolver is not installed", strings.Join(unary, ", ")))
+	}
+	if len(stream) > 0 {
+		sort.Strings(stream)
+		msgs = append(msgs, fmt.Sprintf("stream interceptors of %s are never called as StreamResolver is not installed", strings.Join(stream, ", ")))
+	}
+	err := fmt.Errorf("Invalid wiring: %s", strings.Join(msgs, "; "))
+	switch mode {
+	case WiringWarn:
+		grpclog.Warning(err)
+		return nil
+	case WiringPanic:
+		panic(err)
+	}
+	return err
+}
+
+// serviceRoutes returns the routes of the methods of the services registered
+// on `srv`, split between unary and streaming methods.
+func serviceRoutes(srv *grpc.Server) (unary, stream []string) {
+	for service, info := range srv.GetServiceInfo() {
+		for _, method := range info.Methods {
+			route := "/" + service + "/" + method.Name
+			if method.IsClientStream || method.IsServerStream {
+				stream = append(stream, route)
+			} else {
+				unary = append(unary, route)
+			}
+		}
+	}
+	return
+}
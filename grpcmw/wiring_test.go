@@ -0,0 +1,72 @@
+package grpcmw
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// wiringServer returns a server with the unary method "/pkg.Service/Method"
+// and the stream method "/pkg.Service/Watch".
+func wiringServer() *grpc.Server {
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pkg.Service",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Method"}},
+		Streams:     []grpc.StreamDesc{{StreamName: "Watch", ServerStreams: true}},
+	}, struct{}{})
+	return srv
+}
+
+// wiringRouter returns a router with unary and stream interceptors for the
+// package "pkg".
+func wiringRouter() ServerRouter {
+	var calls []string
+	r := NewServerRouter()
+	pkg, _ := r.RegisterPackage("pkg")
+	pkg.AddGRPCUnaryInterceptor(recordServerUnary(&calls, "unary"))
+	pkg.AddGRPCStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ss)
+	})
+	pkg.Register(NewServerInterceptorRegister("Service"))
+	return r
+}
+
+func TestServerRouterCheckWiring(t *testing.T) {
+	srv := wiringServer()
+	r := wiringRouter()
+	err := r.CheckWiring(srv, WiringError)
+	if err == nil || !strings.Contains(err.Error(), "/pkg.Service/Method") || !strings.Contains(err.Error(), "/pkg.Service/Watch") {
+		t.Fatalf("CheckWiring = %v, want an error for both methods", err)
+	}
+	if err := r.CheckWiring(srv, WiringWarn); err != nil {
+		t.Errorf("CheckWiring(WiringWarn) = %v, want the error to be logged", err)
+	}
+
+	r.UnaryResolver()
+	err = r.CheckWiring(srv, WiringError)
+	if err == nil || strings.Contains(err.Error(), "/pkg.Service/Method") || !strings.Contains(err.Error(), "/pkg.Service/Watch") {
+		t.Fatalf("CheckWiring = %v, want an error for the stream method only", err)
+	}
+	r.StreamResolver()
+	if err := r.CheckWiring(srv, WiringError); err != nil {
+		t.Errorf("CheckWiring = %v, want no error once both resolvers are installed", err)
+	}
+
+	r = wiringRouter()
+	r.ServerOptions()
+	if err := r.CheckWiring(srv, WiringError); err != nil {
+		t.Errorf("CheckWiring = %v, want no error once ServerOptions has been called", err)
+	}
+}
+
+func TestServerRouterCheckWiringPanic(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("CheckWiring(WiringPanic) did not panic")
+		}
+	}()
+	wiringRouter().CheckWiring(wiringServer(), WiringPanic)
+}
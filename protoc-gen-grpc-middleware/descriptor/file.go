@@ -1,6 +1,10 @@
 package descriptor
 
 import (
+	"path"
+	"sort"
+	"strings"
+
 	"github.com/MarquisIO/go-grpcmw/annotations"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 )
@@ -8,6 +12,7 @@ import (
 // File represents a protobuf file.
 type File struct {
 	Package      string
+	GoPackage    string
 	Name         string
 	Services     []*Service
 	Interceptors *Interceptors
@@ -19,9 +24,10 @@ type File struct {
 func GetFile(pb *descriptor.FileDescriptorProto) (f *File, err error) {
 	services := pb.GetService()
 	f = &File{
-		Name:     pb.GetName(),
-		Package:  pb.GetPackage(),
-		Services: make([]*Service, len(services)),
+		Name:      pb.GetName(),
+		Package:   pb.GetPackage(),
+		GoPackage: goPackage(pb),
+		Services:  make([]*Service, len(services)),
 	}
 	if pb.Options != nil {
 		if f.Interceptors, err = GetInterceptors(pb.Options, annotations.E_PackageInterceptors); err != nil {
@@ -38,3 +44,47 @@ func GetFile(pb *descriptor.FileDescriptorProto) (f *File, err error) {
 	}
 	return
 }
+
+// goPackage returns the name of the go package of `pb`, either from its
+// `go_package` option or from the last segment of its protobuf package.
+func goPackage(pb *descriptor.FileDescriptorProto) string {
+	if pkg := pb.GetOptions().GetGoPackage(); len(pkg) > 0 {
+		if idx := strings.LastIndex(pkg, ";"); idx >= 0 {
+			return pkg[idx+1:]
+		}
+		return strings.Map(func(r rune) rune {
+			if r == '.' || r == '-' {
+				return '_'
+			}
+			return r
+		}, path.Base(pkg))
+	}
+	pkg := pb.GetPackage()
+	return pkg[strings.LastIndex(pkg, ".")+1:]
+}
+
+// Indexes returns the sorted indexes of the interceptors referenced by the
+// options of the file, of its services and of their methods.
+func (f *File) Indexes() []string {
+	seen := make(map[string]struct{})
+	add := func(interceptors *Interceptors) {
+		if interceptors != nil {
+			for _, index := range interceptors.Indexes {
+				seen[index] = struct{}{}
+			}
+		}
+	}
+	add(f.Interceptors)
+	for _, service := range f.Services {
+		add(service.Interceptors)
+		for _, method := range service.Methods {
+			add(method.Interceptors)
+		}
+	}
+	indexes := make([]string, 0, len(seen))
+	for index := range seen {
+		indexes = append(indexes, index)
+	}
+	sort.Strings(indexes)
+	return indexes
+}
@@ -11,6 +11,10 @@ import (
 // Interceptors defines interceptors to use.
 type Interceptors struct {
 	Indexes []string
+	Exclude []string
+	// Params holds the parameters given to the factory of each index, by
+	// name.
+	Params map[string]map[string]string
 }
 
 // GetInterceptors extracts the `Interceptors` extension (described by `desc`)
@@ -26,10 +30,39 @@ func GetInterceptors(pb proto.Message, desc *proto.ExtensionDesc) (*Interceptors
 	interceptors, ok := ext.(*annotations.Interceptors)
 	if !ok {
 		return nil, fmt.Errorf("extension is %T; want an Interceptors", ext)
-	} else if len(interceptors.GetIndexes()) == 0 {
+	} else if len(interceptors.GetIndexes()) == 0 && len(interceptors.GetExclude()) == 0 {
 		return nil, nil
 	}
-	return &Interceptors{
+	ret := &Interceptors{
 		Indexes: interceptors.GetIndexes(),
-	}, nil
+		Exclude: interceptors.GetExclude(),
+	}
+	if ret.Params, err = getParams(interceptors); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// getParams groups the parameters of `interceptors` by index. It fails if a
+// parameter is given to an index that is not used.
+func getParams(interceptors *annotations.Interceptors) (map[string]map[string]string, error) {
+	if len(interceptors.GetParams()) == 0 {
+		return nil, nil
+	}
+	params := make(map[string]map[string]string)
+	for _, index := range interceptors.GetIndexes() {
+		params[index] = nil
+	}
+	for _, param := range interceptors.GetParams() {
+		values, used := params[param.GetIndex()]
+		if !used {
+			return nil, fmt.Errorf("parameter %s is given to index %q which is not used", param.GetName(), param.GetIndex())
+		}
+		if values == nil {
+			values = make(map[string]string)
+			params[param.GetIndex()] = values
+		}
+		values[param.GetName()] = param.GetValue()
+	}
+	return params, nil
 }
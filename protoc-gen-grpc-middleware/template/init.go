@@ -2,7 +2,9 @@ package template
 
 import (
 	"bytes"
+	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -17,50 +19,84 @@ const (
 
 // Code templates
 const (
-	initCode = `package {{.Package}}
+	initCode = `package {{.GoPackage}}
 
 import (
 	grpcmw   "github.com/MarquisIO/go-grpcmw/grpcmw"
 	registry "github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+	grpclog  "google.golang.org/grpc/grpclog"
+)
+
+var (
+	_ = grpclog.Warningf
 )
 
 type server{{template "pkgType" .}} struct {
 	grpcmw.ServerInterceptor
+	registry *registry.Registry
 }
 
 type client{{template "pkgType" .}} struct {
 	grpcmw.ClientInterceptor
+	registry *registry.Registry
 }
 
-var pkgInterceptors []string
-{{with .Interceptors}}{{template "pkgInterceptors" .}}{{end}}
+var (
+	pkgInterceptors []string
+	pkgExclusions   []string
+	pkgIndexes      []string
+	pkgParams       = make(map[string]map[string]string)
+)
+{{with .Interceptors}}{{template "pkgInterceptors" .}}{{end}}{{template "pkgIndexes" .}}
 func RegisterServerInterceptors(router grpcmw.ServerRouter) *server{{template "pkgType" .}} {
-	register := router.GetRegister()
-	lvl, ok := register.Get("{{.Package}}")
-	if !ok {
-		lvl = grpcmw.NewServerInterceptorRegister("{{.Package}}")
-		register.Register(lvl)
+	return RegisterServerInterceptorsWithRegistry(router, registry.Default)
+}
+
+func RegisterServerInterceptorsStrict(router grpcmw.ServerRouter, reg *registry.Registry) (*server{{template "pkgType" .}}, error) {
+	if missing := reg.MissingServerInterceptors(pkgIndexes...); len(missing) > 0 {
+		return nil, &registry.ValidationError{Server: missing}
+	}
+	return RegisterServerInterceptorsWithRegistry(router, reg), nil
+}
+
+func RegisterServerInterceptorsWithRegistry(router grpcmw.ServerRouter, reg *registry.Registry) *server{{template "pkgType" .}} {
+	reg.ReferenceServer(pkgIndexes...)
+	lvl, created := router.RegisterPackage("{{.Package}}")
+	if created {
 		for _, interceptor := range pkgInterceptors {
-			lvl.Merge(registry.GetServerInterceptor(interceptor))
+			lvl.Merge(reg.MustNewServerInterceptor(interceptor, pkgParams[interceptor], registry.RouteInfo{Package: "{{.Package}}"}))
 		}
+		lvl.Exclude(pkgExclusions...)
 	}
 	return &server{{template "pkgType" .}}{
 		ServerInterceptor: lvl,
+		registry:          reg,
 	}
 }
 
 func RegisterClientInterceptors(router grpcmw.ClientRouter) *client{{template "pkgType" .}} {
-	register := router.GetRegister()
-	lvl, ok := register.Get("{{.Package}}")
-	if !ok {
-		lvl = grpcmw.NewClientInterceptorRegister("{{.Package}}")
-		register.Register(lvl)
+	return RegisterClientInterceptorsWithRegistry(router, registry.Default)
+}
+
+func RegisterClientInterceptorsStrict(router grpcmw.ClientRouter, reg *registry.Registry) (*client{{template "pkgType" .}}, error) {
+	if missing := reg.MissingClientInterceptors(pkgIndexes...); len(missing) > 0 {
+		return nil, &registry.ValidationError{Client: missing}
+	}
+	return RegisterClientInterceptorsWithRegistry(router, reg), nil
+}
+
+func RegisterClientInterceptorsWithRegistry(router grpcmw.ClientRouter, reg *registry.Registry) *client{{template "pkgType" .}} {
+	reg.ReferenceClient(pkgIndexes...)
+	lvl, created := router.RegisterPackage("{{.Package}}")
+	if created {
 		for _, interceptor := range pkgInterceptors {
-			lvl.Merge(registry.GetClientInterceptor(interceptor))
+			lvl.Merge(reg.MustNewClientInterceptor(interceptor, pkgParams[interceptor], registry.RouteInfo{Package: "{{.Package}}"}))
 		}
+		lvl.Exclude(pkgExclusions...)
 	}
 	return &client{{template "pkgType" .}}{
 		ClientInterceptor: lvl,
+		registry:          reg,
 	}
 }
 
@@ -68,7 +104,37 @@ func RegisterClientInterceptors(router grpcmw.ClientRouter) *client{{template "p
 `
 )
 
-var initCodeTpl = template.Must(template.New(initKey).Parse(initCode))
+var initCodeTpl = template.Must(template.New(initKey).Funcs(funcs).Parse(initCode))
+
+// funcs are the functions available in code templates.
+var funcs = template.FuncMap{
+	"ident":  ident,
+	"params": params,
+}
+
+// ident turns the protobuf package `pkg` into a valid go identifier.
+func ident(pkg string) string {
+	return strings.Replace(pkg, ".", "_", -1)
+}
+
+// params returns the go expression of the parameters given to `index` in
+// `all`, or nil if there are none.
+func params(all map[string]map[string]string, index string) string {
+	values := all[index]
+	if len(values) == 0 {
+		return "nil"
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for idx, name := range names {
+		pairs[idx] = fmt.Sprintf("%q: %q", name, values[name])
+	}
+	return "map[string]string{" + strings.Join(pairs, ", ") + "}"
+}
 
 // Apply applies the given package descriptors and generates the appropriate
 // code using go templates.
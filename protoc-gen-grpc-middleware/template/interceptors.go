@@ -5,6 +5,8 @@ import "text/template"
 // Code template keys
 const (
 	pkgInterceptorsKey = "pkgInterceptors"
+	pkgIndexesKey      = "pkgIndexes"
+	stringsKey         = "strings"
 )
 
 // Code templates
@@ -14,11 +16,28 @@ const (
 	pkgInterceptors = append(
 		pkgInterceptors,{{range .Indexes}}
 		"{{.}}",{{end}}
+	){{$params := .Params}}{{range .Indexes}}{{if index $params .}}
+	pkgParams["{{.}}"] = {{params $params .}}{{end}}{{end}}
+}{{end}}
+{{if .Exclude}}func init() {
+	pkgExclusions = append(
+		pkgExclusions,{{range .Exclude}}
+		"{{.}}",{{end}}
 	)
 }{{end}}
 `
+
+	pkgIndexesCode = `{{with .Indexes}}
+func init() {
+	pkgIndexes = append(pkgIndexes, {{template "strings" .}})
+}
+{{end}}`
+
+	stringsCode = `{{range $idx, $name := .}}{{if $idx}}, {{end}}"{{$name}}"{{end}}`
 )
 
 func init() {
 	template.Must(initCodeTpl.New(pkgInterceptorsKey).Parse(pkgInterceptorsCode))
+	template.Must(initCodeTpl.New(pkgIndexesKey).Parse(pkgIndexesCode))
+	template.Must(initCodeTpl.New(stringsKey).Parse(stringsCode))
 }
@@ -14,21 +14,11 @@ const (
 
 	methodCode = `
 func (s *server{{template "serviceType" .}}) {{.Method}}() grpcmw.{{template "methodType" .Stream}}ServerInterceptor {
-	method, ok := s.ServerInterceptor.(grpcmw.ServerInterceptorRegister).Get("{{.Method}}")
-	if !ok {
-		method = grpcmw.NewServerInterceptorRegister("{{.Method}}")
-		s.ServerInterceptor.(grpcmw.ServerInterceptorRegister).Register(method)
-	}
-	return method.{{template "methodType" .Stream}}ServerInterceptor()
+	return s.level("{{.Method}}").{{template "methodType" .Stream}}ServerInterceptor()
 }
 
 func (s *client{{template "serviceType" .}}) {{.Method}}() grpcmw.{{template "methodType" .Stream}}ClientInterceptor {
-	method, ok := s.ClientInterceptor.(grpcmw.ClientInterceptorRegister).Get("{{.Method}}")
-	if !ok {
-		method = grpcmw.NewClientInterceptorRegister("{{.Method}}")
-		s.ClientInterceptor.(grpcmw.ClientInterceptorRegister).Register(method)
-	}
-	return method.{{template "methodType" .Stream}}ClientInterceptor()
+	return s.level("{{.Method}}").{{template "methodType" .Stream}}ClientInterceptor()
 }`
 )
 
@@ -12,20 +12,22 @@ const (
 
 // Code templates
 const (
-	pkgTypeCode = `Interceptor_{{.Package}}`
+	pkgTypeCode = `Interceptor_{{ident .Package}}`
 
-	pkgCode = `package {{.Package}}
+	pkgCode = `package {{.GoPackage}}
 
 import (
 	grpcmw "github.com/MarquisIO/go-grpcmw/grpcmw"
 	registry "github.com/MarquisIO/go-grpcmw/grpcmw/registry"
+	grpclog "google.golang.org/grpc/grpclog"
 )
 
 var (
 	_ = registry.GetClientInterceptor
+	_ = grpclog.Warningf
 )
 
-{{with .Interceptors}}{{template "pkgInterceptors" .}}{{end}}
+{{with .Interceptors}}{{template "pkgInterceptors" .}}{{end}}{{template "pkgIndexes" .}}
 {{range .Services}}{{template "service" .}}{{end}}
 `
 )
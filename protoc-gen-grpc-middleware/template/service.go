@@ -10,9 +10,9 @@ const (
 
 // Code templates
 const (
-	serviceTypeCode = `Interceptor_{{.Package}}{{.Service}}`
+	serviceTypeCode = `Interceptor_{{ident .Package}}{{.Service}}`
 
-	serviceCode = `
+	serviceCode = `{{$service := .}}
 type server{{template "serviceType" .}} struct {
 	grpcmw.ServerInterceptor
 }
@@ -27,14 +27,16 @@ func (i *server{{template "pkgType" .}}) Register{{.Service}}() *server{{templat
 		ret := &server{{template "serviceType" .}}{
 			ServerInterceptor: grpcmw.NewServerInterceptorRegister("{{.Service}}"),
 		}
-		i.ServerInterceptor.(grpcmw.ServerInterceptorRegister).Register(ret.ServerInterceptor)
-		{{with .Interceptors}}ret.ServerInterceptor.Merge({{range .Indexes}}
-			registry.GetServerInterceptor("{{.}}"),{{end}}
-		){{end}}
-		{{range .Methods}}{{if .Interceptors}}
-		ret.{{.Method}}().AddInterceptor({{$method := .}}{{range .Interceptors.Indexes}}
-			registry.GetServerInterceptor("{{.}}").{{template "methodType" $method.Stream}}ServerInterceptor(),{{end}}
-		){{end}}{{end}}
+		if err := i.ServerInterceptor.(grpcmw.ServerInterceptorRegister).Register(ret.ServerInterceptor); err != nil {
+			grpclog.Warningf("Level {{.Service}} not registered: %v", err)
+		}
+		{{with .Interceptors}}{{$params := .Params}}ret.ServerInterceptor.Merge({{range .Indexes}}
+			i.registry.MustNewServerInterceptor("{{.}}", {{params $params .}}, registry.RouteInfo{Package: "{{$service.Package}}", Service: "{{$service.Service}}"}),{{end}}
+		){{if .Exclude}}
+		ret.ServerInterceptor.Exclude({{template "strings" .Exclude}}){{end}}{{end}}
+		{{range .Methods}}{{if .Interceptors}}{{$method := .}}{{range .Interceptors.Indexes}}
+		ret.{{$method.Method}}().AddNamedInterceptor("{{.}}", i.registry.MustNewServerInterceptor("{{.}}", {{params $method.Interceptors.Params .}}, registry.RouteInfo{Package: "{{$method.Package}}", Service: "{{$method.Service}}", Method: "{{$method.Method}}"}).{{template "methodType" $method.Stream}}ServerInterceptor()){{end}}{{if .Interceptors.Exclude}}
+		ret.level("{{$method.Method}}").Exclude({{template "strings" .Interceptors.Exclude}}){{end}}{{end}}{{end}}
 		return ret
 	}
 	return &server{{template "serviceType" .}}{
@@ -48,14 +50,16 @@ func (i *client{{template "pkgType" .}}) Register{{.Service}}() *client{{templat
 		ret := &client{{template "serviceType" .}}{
 			ClientInterceptor: grpcmw.NewClientInterceptorRegister("{{.Service}}"),
 		}
-		i.ClientInterceptor.(grpcmw.ClientInterceptorRegister).Register(ret.ClientInterceptor)
-		{{with .Interceptors}}ret.ClientInterceptor.Merge({{range .Indexes}}
-			registry.GetClientInterceptor("{{.}}"),{{end}}
-		){{end}}
-		{{range .Methods}}{{if .Interceptors}}
-		ret.{{.Method}}().AddInterceptor({{$method := .}}{{range .Interceptors.Indexes}}
-			registry.GetClientInterceptor("{{.}}").{{template "methodType" $method.Stream}}ClientInterceptor(),{{end}}
-		){{end}}{{end}}
+		if err := i.ClientInterceptor.(grpcmw.ClientInterceptorRegister).Register(ret.ClientInterceptor); err != nil {
+			grpclog.Warningf("Level {{.Service}} not registered: %v", err)
+		}
+		{{with .Interceptors}}{{$params := .Params}}ret.ClientInterceptor.Merge({{range .Indexes}}
+			i.registry.MustNewClientInterceptor("{{.}}", {{params $params .}}, registry.RouteInfo{Package: "{{$service.Package}}", Service: "{{$service.Service}}"}),{{end}}
+		){{if .Exclude}}
+		ret.ClientInterceptor.Exclude({{template "strings" .Exclude}}){{end}}{{end}}
+		{{range .Methods}}{{if .Interceptors}}{{$method := .}}{{range .Interceptors.Indexes}}
+		ret.{{$method.Method}}().AddNamedInterceptor("{{.}}", i.registry.MustNewClientInterceptor("{{.}}", {{params $method.Interceptors.Params .}}, registry.RouteInfo{Package: "{{$method.Package}}", Service: "{{$method.Service}}", Method: "{{$method.Method}}"}).{{template "methodType" $method.Stream}}ClientInterceptor()){{end}}{{if .Interceptors.Exclude}}
+		ret.level("{{$method.Method}}").Exclude({{template "strings" .Interceptors.Exclude}}){{end}}{{end}}{{end}}
 		return ret
 	}
 	return &client{{template "serviceType" .}}{
@@ -63,6 +67,28 @@ func (i *client{{template "pkgType" .}}) Register{{.Service}}() *client{{templat
 	}
 }
 
+func (s *server{{template "serviceType" .}}) level(method string) grpcmw.ServerInterceptor {
+	lvl, ok := s.ServerInterceptor.(grpcmw.ServerInterceptorRegister).Get(method)
+	if !ok {
+		lvl = grpcmw.NewServerInterceptorRegister(method)
+		if err := s.ServerInterceptor.(grpcmw.ServerInterceptorRegister).Register(lvl); err != nil {
+			grpclog.Warningf("Level %s not registered: %v", method, err)
+		}
+	}
+	return lvl
+}
+
+func (s *client{{template "serviceType" .}}) level(method string) grpcmw.ClientInterceptor {
+	lvl, ok := s.ClientInterceptor.(grpcmw.ClientInterceptorRegister).Get(method)
+	if !ok {
+		lvl = grpcmw.NewClientInterceptorRegister(method)
+		if err := s.ClientInterceptor.(grpcmw.ClientInterceptorRegister).Register(lvl); err != nil {
+			grpclog.Warningf("Level %s not registered: %v", method, err)
+		}
+	}
+	return lvl
+}
+
 {{range .Methods}}{{template "method" .}}{{end}}
 `
 )